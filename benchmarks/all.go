@@ -2,15 +2,245 @@ package benchmarks
 
 import (
 	"cloud-z/reporting"
+	"context"
+	"errors"
+	"fmt"
+	"time"
 )
 
-func AllBenchmarks(report *reporting.Report) {
-	// TODO single and multi thread
-	report.Benchmarks = map[string]reporting.BenchmarkReport{
-		"fbench": {
-			Version: 1,
-			Result:  fbench(),
-			Unit:    reporting.Seconds,
+type Options struct {
+	// Bench only runs benchmarks with these names or categories, all when empty
+	Bench []string
+	// SkipBench skips benchmarks with these names or categories
+	SkipBench []string
+	// Runs is how many times repeatable benchmarks run, unless RunBudget is used up first
+	Runs      int
+	RunBudget time.Duration
+	// StorageDirectory enables storage benchmarks on a temporary file in this directory
+	StorageDirectory  string
+	StorageBlockSizes []int
+	StorageSize       int64
+	// StorageQueueDepths is the number of concurrent requests for random I/O benchmarks
+	StorageQueueDepths []int
+	StorageDuration    time.Duration
+	// StorageVerify reads back everything the durability benchmark wrote to catch lost or corrupted writes
+	StorageVerify bool
+	// StorageFiles is the number of small files for the metadata benchmark
+	StorageFiles int
+	// MetadataProbe makes one request to the cloud metadata service, nil when no cloud was detected
+	MetadataProbe func(ctx context.Context) error
+	// DNSName is looked up to measure DNS resolver latency, empty to skip
+	DNSName string
+}
+
+func init() {
+	Register(&funcBenchmark{
+		name:     "fbench",
+		version:  1,
+		category: reporting.CPU,
+		repeat:   true,
+		warmUp: func(ctx context.Context, report *reporting.Report, options Options) error {
+			// let the CPU reach its boost clock
+			_, err := fbench()
+			return err
+		},
+		run: func(ctx context.Context, report *reporting.Report, options Options) (Results, error) {
+			return fbenchScaling(report)
+		},
+	})
+	Register(&funcBenchmark{
+		name:     "crypto",
+		version:  1,
+		category: reporting.CPU,
+		repeat:   true,
+		run: func(ctx context.Context, report *reporting.Report, options Options) (Results, error) {
+			return cryptoBenchmark(ctx, report)
+		},
+	})
+	Register(&funcBenchmark{
+		name:     "integer",
+		version:  1,
+		category: reporting.CPU,
+		repeat:   true,
+		run: func(ctx context.Context, report *reporting.Report, options Options) (Results, error) {
+			return integerBenchmark(ctx)
+		},
+	})
+	Register(&funcBenchmark{
+		name:     "matmul",
+		version:  1,
+		category: reporting.CPU,
+		repeat:   true,
+		run: func(ctx context.Context, report *reporting.Report, options Options) (Results, error) {
+			return matmulBenchmark(ctx, report)
+		},
+	})
+	Register(&funcBenchmark{
+		name:     "memory-latency",
+		version:  1,
+		category: reporting.Memory,
+		repeat:   true,
+		run: func(ctx context.Context, report *reporting.Report, options Options) (Results, error) {
+			return Results{"memory-latency": {Result: memoryLatency(report), Unit: reporting.Nanoseconds}}, nil
+		},
+	})
+	Register(&funcBenchmark{
+		name:     "stream",
+		version:  1,
+		category: reporting.Memory,
+		repeat:   true,
+		run: func(ctx context.Context, report *reporting.Report, options Options) (Results, error) {
+			results := Results{}
+			for name, result := range stream(report) {
+				results[name] = reporting.BenchmarkReport{Result: result, Unit: reporting.GigabytesPerSecond}
+			}
+			return results, nil
+		},
+	})
+	Register(&funcBenchmark{
+		name:     "numa",
+		version:  1,
+		category: reporting.Memory,
+		enabled: func(report *reporting.Report, options Options) bool {
+			return len(report.Numa.Nodes) >= 2
+		},
+		run: func(ctx context.Context, report *reporting.Report, options Options) (Results, error) {
+			// results go in report.Numa as a matrix
+			return nil, numa(report)
+		},
+	})
+	Register(&funcBenchmark{
+		name:     "metadata-latency",
+		version:  1,
+		category: reporting.Network,
+		enabled: func(report *reporting.Report, options Options) bool {
+			return options.MetadataProbe != nil
 		},
+		run: func(ctx context.Context, report *reporting.Report, options Options) (Results, error) {
+			return metadataLatency(ctx, report, options.MetadataProbe)
+		},
+	})
+	Register(&funcBenchmark{
+		name:     "dns-latency",
+		version:  1,
+		category: reporting.Network,
+		enabled: func(report *reporting.Report, options Options) bool {
+			return options.DNSName != ""
+		},
+		run: func(ctx context.Context, report *reporting.Report, options Options) (Results, error) {
+			return dnsLatency(ctx, report, options.DNSName)
+		},
+	})
+	Register(&funcBenchmark{
+		name:     "storage",
+		version:  1,
+		category: reporting.Storage,
+		enabled: func(report *reporting.Report, options Options) bool {
+			return options.StorageDirectory != ""
+		},
+		run: func(ctx context.Context, report *reporting.Report, options Options) (Results, error) {
+			return storage(ctx, report, options)
+		},
+	})
+}
+
+// repeat runs a benchmark options.Runs times or until options.RunBudget is used up, whichever comes first. It always
+// runs at least once. Every result becomes the median of its runs.
+func repeat(ctx context.Context, report *reporting.Report, options Options, benchmark Benchmark) (Results, error) {
+	results := Results{}
+	samples := map[string][]float64{}
+	start := time.Now()
+
+	var err error
+	for run := 0; run < options.Runs; run++ {
+		if run > 0 && (time.Since(start) >= options.RunBudget || ctx.Err() != nil) {
+			break
+		}
+
+		var runResults Results
+		runResults, err = benchmark.Run(ctx, report, options)
+		if err != nil {
+			// keep the runs that worked
+			break
+		}
+		for name, result := range runResults {
+			if _, ok := results[name]; !ok {
+				results[name] = result
+			}
+			samples[name] = append(samples[name], result.Result)
+		}
+	}
+
+	for name, result := range results {
+		result.Statistics = reporting.NewStatistics(samples[name])
+		result.Result = result.Statistics.Median
+		results[name] = result
+	}
+
+	return results, err
+}
+
+// runBenchmark warms up and runs one benchmark, and adds its results to the report.
+func runBenchmark(ctx context.Context, report *reporting.Report, options Options, benchmark Benchmark) error {
+	var results Results
+	var err error
+
+	if warmUpper, ok := benchmark.(WarmUpper); ok {
+		if err = warmUpper.WarmUp(ctx, report, options); err != nil {
+			err = fmt.Errorf("warm-up: %w", err)
+		}
+	}
+
+	if err == nil {
+		if repeatable, ok := benchmark.(Repeatable); ok && repeatable.Repeatable() {
+			results, err = repeat(ctx, report, options, benchmark)
+		} else {
+			results, err = benchmark.Run(ctx, report, options)
+		}
+	}
+
+	var accuracy *accuracyError
+	if errors.As(err, &accuracy) {
+		// even results from runs that passed can't be trusted
+		for _, mismatch := range accuracy.mismatches {
+			report.AddError(fmt.Sprintf("Benchmark %v accuracy check failed: %v", benchmark.Name(), mismatch))
+		}
+		results = Results{benchmark.Name(): {Failed: true}}
+	}
+
+	// partial results are still good
+	for name, result := range results {
+		result.Version = benchmark.Version()
+		result.Category = benchmark.Category()
+		report.AddBenchmark(name, result)
+	}
+
+	return err
+}
+
+// AllBenchmarks runs all selected benchmarks in the order they were registered. It stops early when ctx is cancelled.
+func AllBenchmarks(ctx context.Context, report *reporting.Report, options Options) {
+	report.Benchmarks = map[string]reporting.BenchmarkReport{}
+
+	for _, benchmark := range registry {
+		if !selected(benchmark, options) {
+			continue
+		}
+		if conditional, ok := benchmark.(Conditional); ok && !conditional.Enabled(report, options) {
+			continue
+		}
+		if ctx.Err() != nil {
+			report.AddError("Benchmarks interrupted")
+			return
+		}
+
+		if err := runBenchmark(ctx, report, options, benchmark); err != nil {
+			report.AddError(fmt.Sprintf("Benchmark %v failed: %v", benchmark.Name(), err))
+		}
+	}
+
+	// the last benchmark can be interrupted too, it keeps the results it had
+	if ctx.Err() != nil {
+		report.AddError("Benchmarks interrupted")
 	}
 }
@@ -0,0 +1,190 @@
+package benchmarks
+
+import (
+	"cloud-z/reporting"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Results maps result names to results. A benchmark can have more than one result, like one for every block size.
+type Results map[string]reporting.BenchmarkReport
+
+type Benchmark interface {
+	Name() string
+	// Version must go up whenever results are no longer comparable with older versions
+	Version() int
+	Category() reporting.CategoryType
+	// Run measures and returns results. Problems that don't stop the benchmark can be added to the report as
+	// errors. ctx is cancelled when the user interrupts.
+	Run(ctx context.Context, report *reporting.Report, options Options) (Results, error)
+}
+
+// WarmUpper is implemented by benchmarks that need to do something before measuring, like letting the CPU reach its
+// boost clock.
+type WarmUpper interface {
+	WarmUp(ctx context.Context, report *reporting.Report, options Options) error
+}
+
+// Conditional is implemented by benchmarks that only run sometimes, like opt-in benchmarks or ones that need
+// hardware the instance might not have.
+type Conditional interface {
+	Enabled(report *reporting.Report, options Options) bool
+}
+
+// Repeatable is implemented by benchmarks that are quick enough to run several times. Their results become the median
+// of all runs with statistics to tell a noisy neighbor from a real result.
+type Repeatable interface {
+	Repeatable() bool
+}
+
+// accuracyError is returned by benchmarks that check their own results and got the wrong answer. The benchmark is
+// marked as failed and the report can't be submitted.
+type accuracyError struct {
+	mismatches []string
+}
+
+func (e *accuracyError) Error() string {
+	return "results don't match the reference"
+}
+
+// funcBenchmark implements Benchmark and its optional interfaces with functions.
+type funcBenchmark struct {
+	name     string
+	version  int
+	category reporting.CategoryType
+	repeat   bool
+	run      func(ctx context.Context, report *reporting.Report, options Options) (Results, error)
+	// optional
+	warmUp  func(ctx context.Context, report *reporting.Report, options Options) error
+	enabled func(report *reporting.Report, options Options) bool
+}
+
+func (b *funcBenchmark) Name() string {
+	return b.name
+}
+
+func (b *funcBenchmark) Version() int {
+	return b.version
+}
+
+func (b *funcBenchmark) Category() reporting.CategoryType {
+	return b.category
+}
+
+func (b *funcBenchmark) Repeatable() bool {
+	return b.repeat
+}
+
+func (b *funcBenchmark) Run(ctx context.Context, report *reporting.Report, options Options) (Results, error) {
+	return b.run(ctx, report, options)
+}
+
+func (b *funcBenchmark) WarmUp(ctx context.Context, report *reporting.Report, options Options) error {
+	if b.warmUp == nil {
+		return nil
+	}
+	return b.warmUp(ctx, report, options)
+}
+
+func (b *funcBenchmark) Enabled(report *reporting.Report, options Options) bool {
+	if b.enabled == nil {
+		return true
+	}
+	return b.enabled(report, options)
+}
+
+// opsPerSecond runs an operation in a loop on threads goroutines for duration and returns how many operations per
+// second they did together. setup is called once per goroutine and returns the operation.
+func opsPerSecond(setup func() (func(), error), threads int, duration time.Duration) (float64, error) {
+	ops := make([]func(), threads)
+	for i := range ops {
+		op, err := setup()
+		if err != nil {
+			return 0, err
+		}
+		ops[i] = op
+	}
+
+	counts := make([]int, threads)
+	var wg sync.WaitGroup
+	wg.Add(threads)
+	start := time.Now()
+	deadline := start.Add(duration)
+	for i := range ops {
+		go func(i int) {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				ops[i]()
+				counts[i]++
+			}
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var total int
+	for _, count := range counts {
+		total += count
+	}
+	return float64(total) / elapsed.Seconds(), nil
+}
+
+var registry []Benchmark
+
+// Register adds a benchmark to run. Benchmarks run in the order they were registered.
+func Register(benchmark Benchmark) {
+	for _, existing := range registry {
+		if existing.Name() == benchmark.Name() {
+			panic(fmt.Sprintf("benchmark %v registered twice", benchmark.Name()))
+		}
+	}
+	registry = append(registry, benchmark)
+}
+
+// Registered returns all registered benchmarks.
+func Registered() []Benchmark {
+	return registry
+}
+
+// matches checks if a --bench or --skip-bench value picks the benchmark by name or category.
+func matches(benchmark Benchmark, selectors []string) bool {
+	for _, selector := range selectors {
+		if selector == benchmark.Name() || selector == string(benchmark.Category()) {
+			return true
+		}
+	}
+	return false
+}
+
+func selected(benchmark Benchmark, options Options) bool {
+	if len(options.Bench) > 0 && !matches(benchmark, options.Bench) {
+		return false
+	}
+	return !matches(benchmark, options.SkipBench)
+}
+
+// ValidateSelectors makes sure every selector is a benchmark name or category.
+func ValidateSelectors(selectors []string) error {
+	valid := map[string]bool{}
+	for _, benchmark := range registry {
+		valid[benchmark.Name()] = true
+		valid[string(benchmark.Category())] = true
+	}
+
+	for _, selector := range selectors {
+		if !valid[selector] {
+			var names []string
+			for name := range valid {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("unknown benchmark %v, pick from %v", selector, strings.Join(names, ", "))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package benchmarks
+
+import (
+	"cloud-z/reporting"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"golang.org/x/crypto/chacha20poly1305"
+	"runtime"
+	"time"
+)
+
+/*
+   Cryptography throughput.
+
+   Runs the ciphers, hashes and signatures TLS uses with Go's standard
+   crypto, first on one goroutine and then on one goroutine per logical
+   core. Ciphers and hashes process 16KB buffers, the largest TLS record,
+   and report MB/s. Signatures report operations per second.
+
+   Every result lists the CPU features that can speed it up and that this
+   CPU has. A slow result with no features usually means the hypervisor
+   hides them.
+*/
+
+const cryptoDuration = time.Second
+const cryptoBufferSize = 16 * 1024
+
+type cryptoTest struct {
+	name string
+	unit reporting.UnitType
+	// features that speed this test up, x86 and ARM
+	features []string
+	// setup returns a function that runs one operation. It's called once per goroutine.
+	setup func() (func(), error)
+}
+
+func aeadTest(newAEAD func() (cipher.AEAD, error)) func() (func(), error) {
+	return func() (func(), error) {
+		aead, err := newAEAD()
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		plaintext := make([]byte, cryptoBufferSize)
+		ciphertext := make([]byte, 0, cryptoBufferSize+aead.Overhead())
+		return func() {
+			// nonce reuse is fine as nothing is ever decrypted
+			ciphertext = aead.Seal(ciphertext[:0], nonce, plaintext, nil)
+		}, nil
+	}
+}
+
+func aesGCM(keySize int) func() (cipher.AEAD, error) {
+	return func() (cipher.AEAD, error) {
+		block, err := aes.NewCipher(make([]byte, keySize))
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	}
+}
+
+// chaCha20Poly1305 is the cipher TLS 1.3 picks on CPUs without AES instructions. The standard library doesn't export
+// it, and crypto/tls uses the same x/crypto package vendored.
+func chaCha20Poly1305() (cipher.AEAD, error) {
+	return chacha20poly1305.New(make([]byte, chacha20poly1305.KeySize))
+}
+
+func ecdsaTest(verify bool) func() (func(), error) {
+	return func() (func(), error) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		digest := sha256.Sum256([]byte("cloud-z"))
+		signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		if verify {
+			return func() {
+				ecdsa.VerifyASN1(&key.PublicKey, digest[:], signature)
+			}, nil
+		}
+		return func() {
+			_, _ = ecdsa.SignASN1(rand.Reader, key, digest[:])
+		}, nil
+	}
+}
+
+func ed25519Test(verify bool) func() (func(), error) {
+	return func() (func(), error) {
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		message := []byte("cloud-z")
+		signature := ed25519.Sign(private, message)
+		if verify {
+			return func() {
+				ed25519.Verify(public, message, signature)
+			}, nil
+		}
+		return func() {
+			ed25519.Sign(private, message)
+		}, nil
+	}
+}
+
+func hashTest(sum func(data []byte)) func() (func(), error) {
+	return func() (func(), error) {
+		data := make([]byte, cryptoBufferSize)
+		return func() {
+			sum(data)
+		}, nil
+	}
+}
+
+var cryptoTests = []cryptoTest{
+	{"aes-128-gcm", reporting.MegabytesPerSecond, []string{"AESNI", "CLMUL", "VAES", "VPCLMULQDQ", "AESARM", "PMULL"}, aeadTest(aesGCM(16))},
+	{"aes-256-gcm", reporting.MegabytesPerSecond, []string{"AESNI", "CLMUL", "VAES", "VPCLMULQDQ", "AESARM", "PMULL"}, aeadTest(aesGCM(32))},
+	{"chacha20-poly1305", reporting.MegabytesPerSecond, []string{"AVX2", "AVX512F", "ASIMD"}, aeadTest(chaCha20Poly1305)},
+	{"sha256", reporting.MegabytesPerSecond, []string{"SHA", "AVX2", "SHA2"}, hashTest(func(data []byte) { sha256.Sum256(data) })},
+	{"sha512", reporting.MegabytesPerSecond, []string{"AVX2", "AVX512F", "SHA512"}, hashTest(func(data []byte) { sha512.Sum512(data) })},
+	// the P-256 and edwards25519 assembly only uses instructions every amd64 and arm64 CPU has
+	{"ecdsa-p256-sign", reporting.OperationsPerSecond, []string{}, ecdsaTest(false)},
+	{"ecdsa-p256-verify", reporting.OperationsPerSecond, []string{}, ecdsaTest(true)},
+	{"ed25519-sign", reporting.OperationsPerSecond, []string{}, ed25519Test(false)},
+	{"ed25519-verify", reporting.OperationsPerSecond, []string{}, ed25519Test(true)},
+}
+
+// cpuFeatures returns the features this CPU has out of the given ones.
+func cpuFeatures(report *reporting.Report, features []string) []string {
+	have := map[string]bool{}
+	for _, feature := range report.CPU.Features {
+		have[feature] = true
+	}
+
+	// not nil so results show that nothing was found
+	result := []string{}
+	for _, feature := range features {
+		if have[feature] {
+			result = append(result, feature)
+		}
+	}
+	return result
+}
+
+func cryptoBenchmark(ctx context.Context, report *reporting.Report) (Results, error) {
+	results := Results{}
+	threadCounts := []struct {
+		name    string
+		threads int
+	}{
+		{"single", 1},
+		{"multi", runtime.NumCPU()},
+	}
+
+	for _, test := range cryptoTests {
+		features := cpuFeatures(report, test.features)
+		for _, threadCount := range threadCounts {
+			if ctx.Err() != nil {
+				return results, nil
+			}
+
+			perSecond, err := opsPerSecond(test.setup, threadCount.threads, cryptoDuration)
+			if err != nil {
+				return results, fmt.Errorf("%v: %v", test.name, err)
+			}
+			if test.unit == reporting.MegabytesPerSecond {
+				perSecond = perSecond * cryptoBufferSize / 1e6
+			}
+
+			results[fmt.Sprintf("crypto-%v-%v", test.name, threadCount.name)] = reporting.BenchmarkReport{
+				Result:      perSecond,
+				Unit:        test.unit,
+				CPUFeatures: features,
+			}
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,158 @@
+package benchmarks
+
+import (
+	"bufio"
+	"cloud-z/reporting"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+/*
+   DNS resolution latency.
+
+   Every resolver in /etc/resolv.conf is tested on its own. Cached lookups
+   ask for the same name over and over so the resolver answers from its
+   cache. Uncached lookups ask for a random name under the same domain so
+   the resolver has to go to the authoritative servers every time. Those
+   names don't exist and the NXDOMAIN answer counts as a success.
+
+   The domain must not be DNSSEC-signed. Validating resolvers keep the NSEC
+   records of signed zones and answer NXDOMAIN for any name between them
+   from cache (RFC 8198), so uncached lookups under a signed domain like
+   example.com would measure the cache. The default google.com isn't
+   signed.
+*/
+
+const dnsCachedLookups = 50
+const dnsUncachedLookups = 20
+const dnsTimeout = 2 * time.Second
+
+// readNameservers returns the nameservers from resolv.conf.
+func readNameservers(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var nameservers []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			nameservers = append(nameservers, fields[1])
+		}
+	}
+	return nameservers, scanner.Err()
+}
+
+// nameserverResolver returns a resolver that only talks to the given server.
+func nameserverResolver(server string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialer := net.Dialer{}
+			return dialer.DialContext(ctx, network, net.JoinHostPort(server, "53"))
+		},
+	}
+}
+
+func dnsLookup(ctx context.Context, resolver *net.Resolver, name string, missing bool) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, dnsTimeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := resolver.LookupHost(ctx, name)
+	elapsed := time.Since(start)
+
+	var dnsErr *net.DNSError
+	if missing && errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return elapsed, nil
+	}
+	return elapsed, err
+}
+
+// dnsLatencies runs lookups and returns the latency of the successful ones.
+func dnsLatencies(ctx context.Context, resolver *net.Resolver, count int, name func() string, missing bool) ([]time.Duration, error) {
+	var latencies []time.Duration
+	var lastErr error
+	for i := 0; i < count && ctx.Err() == nil; i++ {
+		latency, err := dnsLookup(ctx, resolver, name(), missing)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		latencies = append(latencies, latency)
+	}
+
+	if len(latencies) < count {
+		return latencies, fmt.Errorf("%v of %v lookups failed: %v", count-len(latencies), count, lastErr)
+	}
+	return latencies, nil
+}
+
+func dnsLatency(ctx context.Context, report *reporting.Report, name string) (Results, error) {
+	nameservers, err := readNameservers("/etc/resolv.conf")
+	if errors.Is(err, os.ErrNotExist) {
+		// Windows
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read DNS resolvers: %v", err)
+	}
+
+	results := Results{}
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	cachedName := func() string {
+		return name
+	}
+	// the trailing dot keeps resolv.conf search domains from being tried first, which would add lookups to the latency
+	uncachedName := func() string {
+		return fmt.Sprintf("cloud-z-%016x.%v.", random.Uint64(), strings.TrimSuffix(name, "."))
+	}
+
+	// resolver addresses would give away the network layout so they are numbered instead
+	for i, nameserver := range nameservers {
+		resolver := nameserverResolver(nameserver)
+		prefix := fmt.Sprintf("dns-resolver%v", i+1)
+
+		// fill the cache
+		_, _ = dnsLookup(ctx, resolver, name, false)
+
+		lookups := []struct {
+			name    string
+			count   int
+			lookup  func() string
+			missing bool
+		}{
+			{"cached", dnsCachedLookups, cachedName, false},
+			{"uncached", dnsUncachedLookups, uncachedName, true},
+		}
+
+		for _, lookup := range lookups {
+			latencies, err := dnsLatencies(ctx, resolver, lookup.count, lookup.lookup, lookup.missing)
+			if err != nil {
+				report.AddError(fmt.Sprintf("DNS resolver %v %v latency: %v", i+1, lookup.name, err))
+			}
+			if len(latencies) == 0 {
+				continue
+			}
+
+			distribution := reporting.NewLatencyDistribution(latencies)
+			results[prefix+"-"+lookup.name] = reporting.BenchmarkReport{
+				Result:       distribution.P50,
+				Unit:         reporting.Microseconds,
+				Distribution: distribution,
+			}
+		}
+	}
+
+	return results, nil
+}
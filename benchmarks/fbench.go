@@ -36,41 +36,45 @@ const max_surfaces = 10
 
 /*  Local variables  */
 
-var current_surfaces int16
-var paraxial int16
+/* Every run has its own state so several can run at the same time. */
 
-var clear_aperture float64
+type fbenchState struct {
+	current_surfaces int16
+	paraxial         int16
 
-var aberr_lspher float64
-var aberr_osc float64
-var aberr_lchrom float64
+	clear_aperture float64
 
-var max_lspher float64
-var max_osc float64
-var max_lchrom float64
+	aberr_lspher float64
+	aberr_osc    float64
+	aberr_lchrom float64
 
-var radius_of_curvature float64
-var object_distance float64
-var ray_height float64
-var axis_slope_angle float64
-var from_index float64
-var to_index float64
+	max_lspher float64
+	max_osc    float64
+	max_lchrom float64
 
-var spectral_line [9]float64
-var s [max_surfaces][5]float64
-var od_sa [2][2]float64
+	radius_of_curvature float64
+	object_distance     float64
+	ray_height          float64
+	axis_slope_angle    float64
+	from_index          float64
+	to_index            float64
 
-var outarr [8]string /* Computed output of program goes here */
+	spectral_line [9]float64
+	s             [max_surfaces][5]float64
+	od_sa         [2][2]float64
 
-var itercount int /* The iteration counter for the main loop
-   in the program is made global so that
-   the compiler should not be allowed to
-   optimise out the loop over the ray
-   tracing code. */
+	outarr [8]string /* Computed output of program goes here */
 
-const ITERATIONS = 10000
+	itercount int /* The iteration counter for the main loop
+	   in the program is kept in the state so that
+	   the compiler should not be allowed to
+	   optimise out the loop over the ray
+	   tracing code. */
 
-var niter int = ITERATIONS /* Iteration counter */
+	niter int /* Iteration counter */
+}
+
+const ITERATIONS = 1000000
 
 /* Reference results.  These happen to
    be derived from a run on Microsoft
@@ -135,7 +139,7 @@ axis_slope_angle         Angle incoming ray makes with axis
 
 */
 
-func transit_surface() {
+func (f *fbenchState) transit_surface() {
 	var iang float64
 	var rang float64     /* Refraction angle */
 	var iang_sin float64 /* Incidence angle sin */
@@ -143,118 +147,121 @@ func transit_surface() {
 	var old_axis_slope_angle float64
 	var sagitta float64
 
-	if paraxial > 0 {
-		if radius_of_curvature != 0.0 {
-			if object_distance == 0.0 {
-				axis_slope_angle = 0.0
-				iang_sin = ray_height / radius_of_curvature
+	if f.paraxial > 0 {
+		if f.radius_of_curvature != 0.0 {
+			if f.object_distance == 0.0 {
+				f.axis_slope_angle = 0.0
+				iang_sin = f.ray_height / f.radius_of_curvature
 			} else {
-				iang_sin = ((object_distance -
-					radius_of_curvature) / radius_of_curvature) *
-					axis_slope_angle
+				iang_sin = ((f.object_distance -
+					f.radius_of_curvature) / f.radius_of_curvature) *
+					f.axis_slope_angle
 			}
 
-			rang_sin = (from_index / to_index) *
+			rang_sin = (f.from_index / f.to_index) *
 				iang_sin
-			old_axis_slope_angle = axis_slope_angle
-			axis_slope_angle = axis_slope_angle +
+			old_axis_slope_angle = f.axis_slope_angle
+			f.axis_slope_angle = f.axis_slope_angle +
 				iang_sin - rang_sin
-			if object_distance != 0.0 {
-				ray_height = object_distance * old_axis_slope_angle
+			if f.object_distance != 0.0 {
+				f.ray_height = f.object_distance * old_axis_slope_angle
 			}
-			object_distance = ray_height / axis_slope_angle
+			f.object_distance = f.ray_height / f.axis_slope_angle
 			return
 		}
-		object_distance = object_distance * (to_index / from_index)
-		axis_slope_angle = axis_slope_angle * (from_index / to_index)
+		f.object_distance = f.object_distance * (f.to_index / f.from_index)
+		f.axis_slope_angle = f.axis_slope_angle * (f.from_index / f.to_index)
 		return
 	}
 
-	if radius_of_curvature != 0.0 {
-		if object_distance == 0.0 {
-			axis_slope_angle = 0.0
-			iang_sin = ray_height / radius_of_curvature
+	if f.radius_of_curvature != 0.0 {
+		if f.object_distance == 0.0 {
+			f.axis_slope_angle = 0.0
+			iang_sin = f.ray_height / f.radius_of_curvature
 		} else {
-			iang_sin = ((object_distance -
-				radius_of_curvature) / radius_of_curvature) *
-				Sin(axis_slope_angle)
+			iang_sin = ((f.object_distance -
+				f.radius_of_curvature) / f.radius_of_curvature) *
+				Sin(f.axis_slope_angle)
 		}
 		iang = Asin(iang_sin)
-		rang_sin = (from_index / to_index) *
+		rang_sin = (f.from_index / f.to_index) *
 			iang_sin
-		old_axis_slope_angle = axis_slope_angle
-		axis_slope_angle = axis_slope_angle +
+		old_axis_slope_angle = f.axis_slope_angle
+		f.axis_slope_angle = f.axis_slope_angle +
 			iang - Asin(rang_sin)
 		sagitta = Sin((old_axis_slope_angle + iang) / 2.0)
-		sagitta = 2.0 * radius_of_curvature * sagitta * sagitta
-		object_distance = ((radius_of_curvature * Sin(
+		sagitta = 2.0 * f.radius_of_curvature * sagitta * sagitta
+		f.object_distance = ((f.radius_of_curvature * Sin(
 			old_axis_slope_angle+iang)) *
-			cot(axis_slope_angle)) + sagitta
+			cot(f.axis_slope_angle)) + sagitta
 		return
 	}
 
-	rang = -Asin((from_index / to_index) *
-		Sin(axis_slope_angle))
-	object_distance = object_distance * ((to_index *
-		Cos(-rang)) / (from_index *
-		Cos(axis_slope_angle)))
-	axis_slope_angle = -rang
+	rang = -Asin((f.from_index / f.to_index) *
+		Sin(f.axis_slope_angle))
+	f.object_distance = f.object_distance * ((f.to_index *
+		Cos(-rang)) / (f.from_index *
+		Cos(f.axis_slope_angle)))
+	f.axis_slope_angle = -rang
 }
 
 /*  Perform ray trace in specific spectral line  */
 
-func trace_line(line int, ray_h float64) {
+func (f *fbenchState) trace_line(line int, ray_h float64) {
 
 	var i int16
 
-	object_distance = 0.0
-	ray_height = ray_h
-	from_index = 1.0
-
-	for i = 1; i <= current_surfaces; i++ {
-		radius_of_curvature = s[i][1]
-		to_index = s[i][2]
-		if to_index > 1.0 {
-			to_index = to_index + ((spectral_line[4]-
-				spectral_line[line])/
-				(spectral_line[3]-spectral_line[6]))*((s[i][2]-1.0)/
-				s[i][3])
+	f.object_distance = 0.0
+	f.ray_height = ray_h
+	f.from_index = 1.0
+
+	for i = 1; i <= f.current_surfaces; i++ {
+		f.radius_of_curvature = f.s[i][1]
+		f.to_index = f.s[i][2]
+		if f.to_index > 1.0 {
+			f.to_index = f.to_index + ((f.spectral_line[4]-
+				f.spectral_line[line])/
+				(f.spectral_line[3]-f.spectral_line[6]))*((f.s[i][2]-1.0)/
+				f.s[i][3])
 		}
-		transit_surface()
-		from_index = to_index
-		if i < current_surfaces {
-			object_distance = object_distance - s[i][4]
+		f.transit_surface()
+		f.from_index = f.to_index
+		if i < f.current_surfaces {
+			f.object_distance = f.object_distance - f.s[i][4]
 		}
 	}
 }
 
 /*  Initialise when called the first time  */
 
-func fbench() float64 {
-	var errors int32
+func fbench() (float64, error) {
+	return (&fbenchState{}).run()
+}
+
+func (f *fbenchState) run() (float64, error) {
 	var od_fline float64
 	var od_cline float64
 
-	spectral_line[1] = 7621.0   /* A */
-	spectral_line[2] = 6869.955 /* B */
-	spectral_line[3] = 6562.816 /* C */
-	spectral_line[4] = 5895.944 /* D */
-	spectral_line[5] = 5269.557 /* E */
-	spectral_line[6] = 4861.344 /* F */
-	spectral_line[7] = 4340.477 /* G'*/
-	spectral_line[8] = 3968.494 /* H */
+	f.spectral_line[1] = 7621.0   /* A */
+	f.spectral_line[2] = 6869.955 /* B */
+	f.spectral_line[3] = 6562.816 /* C */
+	f.spectral_line[4] = 5895.944 /* D */
+	f.spectral_line[5] = 5269.557 /* E */
+	f.spectral_line[6] = 4861.344 /* F */
+	f.spectral_line[7] = 4340.477 /* G'*/
+	f.spectral_line[8] = 3968.494 /* H */
 
-	niter = 1000000
+	f.niter = ITERATIONS
 
 	/* Load test case into working array */
 
-	clear_aperture = 4.0
-	current_surfaces = 4
+	f.clear_aperture = 4.0
+	f.current_surfaces = 4
 	var i int16
-	for i = 0; i < current_surfaces; i++ {
+	for i = 0; i < f.current_surfaces; i++ {
 		for j := 0; j < 4; j++ {
 			{
-				s[i+1][j+1] = testcase[i][j]
+				f.s[i+1][j+1] = testcase[i][j]
 			}
 		}
 	}
@@ -263,120 +270,98 @@ func fbench() float64 {
 
 	/* Perform ray trace the specified number of times. */
 
-	for itercount = 0; itercount < niter; itercount++ {
+	for f.itercount = 0; f.itercount < f.niter; f.itercount++ {
 
-		for paraxial = 0; paraxial <= 1; paraxial++ {
+		for f.paraxial = 0; f.paraxial <= 1; f.paraxial++ {
 
 			/* Do main trace in D light */
 
-			trace_line(4, clear_aperture/2.0)
-			od_sa[paraxial][0] = object_distance
-			od_sa[paraxial][1] = axis_slope_angle
+			f.trace_line(4, f.clear_aperture/2.0)
+			f.od_sa[f.paraxial][0] = f.object_distance
+			f.od_sa[f.paraxial][1] = f.axis_slope_angle
 		}
-		paraxial = 0
+		f.paraxial = 0
 
 		/* Trace marginal ray in C */
 
-		trace_line(3, clear_aperture/2.0)
-		od_cline = object_distance
+		f.trace_line(3, f.clear_aperture/2.0)
+		od_cline = f.object_distance
 
 		/* Trace marginal ray in F */
 
-		trace_line(6, clear_aperture/2.0)
-		od_fline = object_distance
+		f.trace_line(6, f.clear_aperture/2.0)
+		od_fline = f.object_distance
 
 		// Compute aberrations of the design
 
 		/* The longitudinal spherical aberration is just the
 		   difference between where the D line comes to focus
 		   for paraxial and marginal rays. */
-		aberr_lspher = od_sa[1][0] - od_sa[0][0]
+		f.aberr_lspher = f.od_sa[1][0] - f.od_sa[0][0]
 
 		/* The offense against the sine condition is a measure
 		   of the degree of coma in the design.  We compute it
 		   as the lateral distance in the focal plane between
 		   where a paraxial ray and marginal ray in the D line
 		   come to focus. */
-		aberr_osc = 1.0 - (od_sa[1][0]*od_sa[1][1])/
-			(Sin(od_sa[0][1])*od_sa[0][0])
+		f.aberr_osc = 1.0 - (f.od_sa[1][0]*f.od_sa[1][1])/
+			(Sin(f.od_sa[0][1])*f.od_sa[0][0])
 
 		/* The axial chromatic aberration is the distance between
 		   where marginal rays in the C and F lines come to focus. */
-		aberr_lchrom = od_fline - od_cline
+		f.aberr_lchrom = od_fline - od_cline
 
 		// Compute maximum acceptable values for each aberration
 
-		max_lspher = Sin(od_sa[0][1])
+		f.max_lspher = Sin(f.od_sa[0][1])
 
 		/* Maximum longitudinal spherical aberration, which is
 		   also the maximum for axial chromatic aberration.  This
 		   is computed for the D line. */
-		max_lspher = 0.0000926 / (max_lspher * max_lspher)
-		max_lchrom = max_lspher
-		max_osc = 0.0025 // Max sine condition offence is constant
+		f.max_lspher = 0.0000926 / (f.max_lspher * f.max_lspher)
+		f.max_lchrom = f.max_lspher
+		f.max_osc = 0.0025 // Max sine condition offence is constant
 	}
 
 	elapsedtime := time.Since(starttime) // timing
 
 	/* Now evaluate the accuracy of the results from the last ray trace */
 
-	outarr[0] = fmt.Sprintf("%15s   %21.11f  %14.11f",
-		"Marginal ray", od_sa[0][0], od_sa[0][1])
-	outarr[1] = fmt.Sprintf("%15s   %21.11f  %14.11f",
-		"Paraxial ray", od_sa[1][0], od_sa[1][1])
-	outarr[2] = fmt.Sprintf(
+	f.outarr[0] = fmt.Sprintf("%15s   %21.11f  %14.11f",
+		"Marginal ray", f.od_sa[0][0], f.od_sa[0][1])
+	f.outarr[1] = fmt.Sprintf("%15s   %21.11f  %14.11f",
+		"Paraxial ray", f.od_sa[1][0], f.od_sa[1][1])
+	f.outarr[2] = fmt.Sprintf(
 		"Longitudinal spherical aberration:      %16.11f",
-		aberr_lspher)
-	outarr[3] = fmt.Sprintf(
+		f.aberr_lspher)
+	f.outarr[3] = fmt.Sprintf(
 		"    (Maximum permissible):              %16.11f",
-		max_lspher)
-	outarr[4] = fmt.Sprintf(
+		f.max_lspher)
+	f.outarr[4] = fmt.Sprintf(
 		"Offense against sine condition (coma):  %16.11f",
-		aberr_osc)
-	outarr[5] = fmt.Sprintf(
+		f.aberr_osc)
+	f.outarr[5] = fmt.Sprintf(
 		"    (Maximum permissible):              %16.11f",
-		max_osc)
-	outarr[6] = fmt.Sprintf(
+		f.max_osc)
+	f.outarr[6] = fmt.Sprintf(
 		"Axial chromatic aberration:             %16.11f",
-		aberr_lchrom)
-	outarr[7] = fmt.Sprintf(
+		f.aberr_lchrom)
+	f.outarr[7] = fmt.Sprintf(
 		"    (Maximum permissible):              %16.11f",
-		max_lchrom)
+		f.max_lchrom)
 
 	/* Now compare the edited results with the master values from
 	   reference executions of this program. */
 
-	errors = 0
+	var mismatches []string
 	for i = 0; i < 8; i++ {
-		if outarr[i] != refarr[i] {
-			fmt.Printf("\nError in results on line %d...\n", i+1)
-			fmt.Printf("Expected:  \"%s\"\n", refarr[i])
-			fmt.Printf("Received:  \"%s\"\n", outarr[i])
-			fmt.Printf("(Errors)    ")
-			k := len(refarr[i])
-			for j := 0; j < k; j++ {
-				if refarr[i][j] == outarr[i][j] {
-					fmt.Printf(" ")
-				} else {
-					fmt.Printf("^") // indicate character where data did not compare.
-				}
-				if refarr[i][j] != outarr[i][j] {
-					errors++
-				}
-			}
-			fmt.Printf("\n")
+		if f.outarr[i] != refarr[i] {
+			mismatches = append(mismatches, fmt.Sprintf("line %d expected \"%s\" but got \"%s\"",
+				i+1, refarr[i], f.outarr[i]))
 		}
 	}
-	if errors > 0 {
-		plural := ""
-		if errors > 1 {
-			plural = "s"
-		}
-		fmt.Printf("\n%d error%s in results.  This is VERY SERIOUS.\n",
-			errors, plural)
-
-		return -1
-	} else {
-		return elapsedtime.Seconds()
+	if len(mismatches) > 0 {
+		return 0, &accuracyError{mismatches: mismatches}
 	}
+	return elapsedtime.Seconds(), nil
 }
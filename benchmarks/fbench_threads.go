@@ -0,0 +1,101 @@
+package benchmarks
+
+import (
+	"cloud-z/reporting"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+   fbench thread scaling.
+
+   Runs one fbench per goroutine with 1 goroutine, one per physical core
+   and one per logical core. Throughput is ray trace iterations per second
+   over all goroutines. Scaling efficiency is the throughput divided by
+   the single thread throughput times the number of goroutines. Going from
+   physical to logical cores shows what hyperthreads are worth.
+*/
+
+// fbenchThreadCounts returns 1, the number of physical cores and the number of logical cores without duplicates.
+func fbenchThreadCounts(report *reporting.Report) []int {
+	logical := runtime.NumCPU()
+	counts := map[int]bool{1: true, logical: true}
+	if physical := report.CPU.PhysicalCores; physical > 0 && physical < logical {
+		counts[physical] = true
+	}
+
+	var result []int
+	for count := range counts {
+		result = append(result, count)
+	}
+	sort.Ints(result)
+	return result
+}
+
+// fbenchParallel runs fbench on threads goroutines at the same time and returns how long it took for all of them to
+// finish.
+func fbenchParallel(threads int) (float64, error) {
+	errs := make([]error, threads)
+	var start sync.WaitGroup
+	var done sync.WaitGroup
+	start.Add(1)
+	done.Add(threads)
+
+	for i := 0; i < threads; i++ {
+		go func(i int) {
+			defer done.Done()
+			start.Wait()
+			_, errs[i] = fbench()
+		}(i)
+	}
+
+	startTime := time.Now()
+	start.Done()
+	done.Wait()
+	elapsed := time.Since(startTime)
+
+	for _, err := range errs {
+		if err != nil {
+			return 0, err
+		}
+	}
+	return elapsed.Seconds(), nil
+}
+
+// fbenchScaling returns the single thread fbench time, and throughput and scaling efficiency for every thread count.
+// Wrong results on any thread fail everything as the CPU can't be trusted.
+func fbenchScaling(report *reporting.Report) (Results, error) {
+	results := Results{}
+	var single float64
+
+	for _, threads := range fbenchThreadCounts(report) {
+		elapsed, err := fbenchParallel(threads)
+		if err != nil {
+			return nil, err
+		}
+		if threads == 1 {
+			results["fbench"] = reporting.BenchmarkReport{Result: elapsed, Unit: reporting.Seconds}
+		}
+
+		throughput := float64(threads*ITERATIONS) / elapsed
+		results[fmt.Sprintf("fbench-p%v", threads)] = reporting.BenchmarkReport{
+			Result: throughput,
+			Unit:   reporting.OperationsPerSecond,
+		}
+
+		if threads == 1 {
+			single = throughput
+		} else if single > 0 {
+			results[fmt.Sprintf("fbench-scaling-p%v", threads)] = reporting.BenchmarkReport{
+				Result: 100 * throughput / (single * float64(threads)),
+				Unit:   reporting.Percent,
+				Better: reporting.HigherIsBetter,
+			}
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,90 @@
+package benchmarks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+/*
+   Filesystem metadata benchmark.
+
+   Creates lots of small files in nested directories and then stats, renames
+   and deletes all of them. This is what CI builds and package managers spend
+   most of their time doing, and it depends more on the filesystem than on
+   the device under it.
+*/
+
+const fsopsFilesPerDirectory = 100
+const fsopsFileSize = 1024
+
+func fsopsPath(root string, file int) string {
+	directory := file / fsopsFilesPerDirectory
+	return filepath.Join(
+		root,
+		fmt.Sprintf("d%v", directory/fsopsFilesPerDirectory),
+		fmt.Sprintf("d%v", directory%fsopsFilesPerDirectory),
+		fmt.Sprintf("f%v", file%fsopsFilesPerDirectory),
+	)
+}
+
+// fsopsPhase runs op on every file and returns operations per second.
+func fsopsPhase(ctx context.Context, files int, op func(file int) error) (float64, error) {
+	start := time.Now()
+	for file := 0; file < files; file++ {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		if err := op(file); err != nil {
+			return 0, err
+		}
+	}
+	return float64(files) / time.Since(start).Seconds(), nil
+}
+
+// storageMetadata measures create, stat, rename and unlink rates on options.StorageFiles small files.
+func storageMetadata(ctx context.Context, directory string, options Options) (map[string]float64, error) {
+	root := filepath.Join(directory, "files")
+	defer os.RemoveAll(root)
+
+	files := options.StorageFiles
+	for file := 0; file < files; file += fsopsFilesPerDirectory {
+		if err := os.MkdirAll(filepath.Dir(fsopsPath(root, file)), 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	content := make([]byte, fsopsFileSize)
+	phases := []struct {
+		name string
+		op   func(file int) error
+	}{
+		{"create", func(file int) error {
+			return os.WriteFile(fsopsPath(root, file), content, 0600)
+		}},
+		{"stat", func(file int) error {
+			_, err := os.Lstat(fsopsPath(root, file))
+			return err
+		}},
+		{"rename", func(file int) error {
+			path := fsopsPath(root, file)
+			return os.Rename(path, path+"r")
+		}},
+		{"unlink", func(file int) error {
+			return os.Remove(fsopsPath(root, file) + "r")
+		}},
+	}
+
+	results := map[string]float64{}
+	for _, phase := range phases {
+		result, err := fsopsPhase(ctx, files, phase.op)
+		if err != nil {
+			return results, err
+		}
+		results["storage-meta-"+phase.name] = result
+	}
+
+	return results, nil
+}
@@ -0,0 +1,145 @@
+package benchmarks
+
+import (
+	"bytes"
+	"cloud-z/reporting"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+/*
+   Write durability benchmark.
+
+   Small records are appended to a file and each one is followed by fsync or
+   fdatasync, the same pattern a database write-ahead log uses. Optionally the
+   whole file is read back with O_DIRECT afterwards to make sure every
+   acknowledged record can be read back intact from below the page cache.
+   That catches lost, misplaced and corrupted writes, but not devices that
+   acknowledge writes before they are durable, because their volatile cache
+   serves the reads too and only a power loss would tell the difference.
+   A mismatch fails the benchmark so the report can't be submitted.
+*/
+
+const walRecordSize = 4096
+
+type syncMode struct {
+	name string
+	sync func(f *os.File) error
+}
+
+var syncModes = []syncMode{
+	{"fsync", func(f *os.File) error { return f.Sync() }},
+	{"fdatasync", fdatasync},
+}
+
+// walRecord fills buffer with a record that can be told apart from every other record.
+func walRecord(buffer []byte, sequence uint64) {
+	for i := range buffer {
+		buffer[i] = byte(sequence)
+	}
+	binary.LittleEndian.PutUint64(buffer, sequence)
+}
+
+func walAppend(ctx context.Context, path string, mode syncMode, duration time.Duration) (reporting.BenchmarkReport, uint64, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0600)
+	if err != nil {
+		return reporting.BenchmarkReport{}, 0, err
+	}
+	defer f.Close()
+
+	buffer := make([]byte, walRecordSize)
+	var latencies []time.Duration
+	records := uint64(0)
+	deadline := time.Now().Add(duration)
+
+	start := time.Now()
+	for time.Now().Before(deadline) {
+		if ctx.Err() != nil {
+			return reporting.BenchmarkReport{}, records, ctx.Err()
+		}
+		walRecord(buffer, records)
+		recordStart := time.Now()
+		if _, err := f.Write(buffer); err != nil {
+			return reporting.BenchmarkReport{}, records, err
+		}
+		if err := mode.sync(f); err != nil {
+			return reporting.BenchmarkReport{}, records, err
+		}
+		latencies = append(latencies, time.Since(recordStart))
+		records++
+	}
+	elapsed := time.Since(start)
+
+	return reporting.BenchmarkReport{
+		Result:       float64(records) / elapsed.Seconds(),
+		Unit:         reporting.OperationsPerSecond,
+		Distribution: reporting.NewLatencyDistribution(latencies),
+	}, records, nil
+}
+
+// walVerify reads the log back bypassing the page cache and returns a description of every bad record. It can't tell
+// whether a record is durable, only whether it was stored where and as it was written.
+func walVerify(path string, records uint64) ([]string, error) {
+	f, direct, err := openDirect(path, os.O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if !direct {
+		if err := dropCache(f); err != nil {
+			return nil, err
+		}
+	}
+
+	var mismatches []string
+	buffer := alignedBuffer(walRecordSize)
+	expected := make([]byte, walRecordSize)
+	for sequence := uint64(0); sequence < records; sequence++ {
+		if _, err := io.ReadFull(f, buffer); err != nil {
+			mismatches = append(mismatches, fmt.Sprintf("record %v of %v is missing: %v", sequence, records, err))
+			break
+		}
+		walRecord(expected, sequence)
+		if !bytes.Equal(buffer, expected) {
+			mismatches = append(mismatches, fmt.Sprintf("record %v of %v doesn't match what was written", sequence, records))
+		}
+	}
+
+	return mismatches, nil
+}
+
+// storageFsync measures appends followed by fsync and by fdatasync.
+func storageFsync(ctx context.Context, report *reporting.Report, directory string, options Options) (map[string]reporting.BenchmarkReport, error) {
+	path := filepath.Join(directory, "wal.tmp")
+	defer os.Remove(path)
+
+	results := map[string]reporting.BenchmarkReport{}
+	for _, mode := range syncModes {
+		result, records, err := walAppend(ctx, path, mode, options.StorageDuration)
+		if err != nil {
+			return results, err
+		}
+		results["storage-"+mode.name] = result
+
+		if options.StorageVerify {
+			mismatches, err := walVerify(path, records)
+			if err != nil {
+				return results, err
+			}
+			for _, mismatch := range mismatches {
+				report.AddError(fmt.Sprintf("Storage %v verification failed: %v", mode.name, mismatch))
+			}
+			if len(mismatches) > 0 {
+				results["storage-"+mode.name] = reporting.BenchmarkReport{Failed: true}
+			}
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,212 @@
+package benchmarks
+
+import (
+	"bytes"
+	"cloud-z/reporting"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	_ "embed"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/big"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+/*
+   Integer throughput.
+
+   Typical server work that barely touches floating point: compression,
+   sorting, big number math and checksums. Compression and checksums run
+   on a fixed synthetic web server access log embedded in the binary so
+   every instance does exactly the same work. Decompressed data is checked
+   against the original.
+
+   The score is the geometric mean of every result divided by its
+   reference value, times 1000. The reference values are fixed so scores
+   stay comparable between instances. Changing them or the corpus needs a
+   new version.
+*/
+
+//go:embed integer_corpus.log
+var integerCorpus []byte
+
+const integerDuration = 500 * time.Millisecond
+const integerSortLength = 1 << 20
+const integerBigBits = 1 << 16
+
+type integerTest struct {
+	name string
+	unit reporting.UnitType
+	// work done by one operation in bytes for MB/s or items for ops/s
+	work      float64
+	reference float64
+	// setup returns a function that runs one operation
+	setup func() (func(), error)
+}
+
+// compressTest compresses the corpus with w, which is reset to write into a buffer every time.
+func compressTest(newWriter func(w io.Writer) (io.WriteCloser, func(w io.Writer))) func() (func(), error) {
+	return func() (func(), error) {
+		var compressed bytes.Buffer
+		writer, reset := newWriter(&compressed)
+		return func() {
+			compressed.Reset()
+			reset(&compressed)
+			_, _ = writer.Write(integerCorpus)
+			_ = writer.Close()
+		}, nil
+	}
+}
+
+func flateWriter(w io.Writer) (io.WriteCloser, func(w io.Writer)) {
+	writer, _ := flate.NewWriter(w, flate.DefaultCompression)
+	return writer, writer.Reset
+}
+
+func gzipWriter(w io.Writer) (io.WriteCloser, func(w io.Writer)) {
+	writer := gzip.NewWriter(w)
+	return writer, writer.Reset
+}
+
+// decompressTest compresses the corpus once and then decompresses it with a reader from newReader. The first
+// decompression is compared with the corpus.
+func decompressTest(newWriter func(w io.Writer) (io.WriteCloser, func(w io.Writer)), newReader func(r io.Reader) (io.Reader, error)) func() (func(), error) {
+	return func() (func(), error) {
+		var compressed bytes.Buffer
+		writer, _ := newWriter(&compressed)
+		if _, err := writer.Write(integerCorpus); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+
+		var decompressed bytes.Buffer
+		decompress := func() error {
+			reader, err := newReader(bytes.NewReader(compressed.Bytes()))
+			if err != nil {
+				return err
+			}
+			decompressed.Reset()
+			_, err = decompressed.ReadFrom(reader)
+			return err
+		}
+
+		if err := decompress(); err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(decompressed.Bytes(), integerCorpus) {
+			return nil, &accuracyError{mismatches: []string{"decompressed data doesn't match the original"}}
+		}
+
+		return func() {
+			_ = decompress()
+		}, nil
+	}
+}
+
+func flateReader(r io.Reader) (io.Reader, error) {
+	return flate.NewReader(r), nil
+}
+
+func gzipReader(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+func sortTest() (func(), error) {
+	random := rand.New(rand.NewSource(1))
+	original := make([]int, integerSortLength)
+	for i := range original {
+		original[i] = random.Int()
+	}
+
+	work := make([]int, len(original))
+	copy(work, original)
+	sort.Ints(work)
+	if !sort.IntsAreSorted(work) {
+		return nil, &accuracyError{mismatches: []string{"sorted slice is out of order"}}
+	}
+
+	return func() {
+		copy(work, original)
+		sort.Ints(work)
+	}, nil
+}
+
+func bigMulTest() (func(), error) {
+	random := rand.New(rand.NewSource(1))
+	limit := new(big.Int).Lsh(big.NewInt(1), integerBigBits)
+	a := new(big.Int).Rand(random, limit)
+	b := new(big.Int).Rand(random, limit)
+	product := new(big.Int)
+
+	return func() {
+		product.Mul(a, b)
+	}, nil
+}
+
+func crc32Test() (func(), error) {
+	return func() {
+		crc32.ChecksumIEEE(integerCorpus)
+	}, nil
+}
+
+func fnvTest() (func(), error) {
+	hash := fnv.New64a()
+	return func() {
+		hash.Reset()
+		_, _ = hash.Write(integerCorpus)
+		hash.Sum64()
+	}, nil
+}
+
+var integerTests = []integerTest{
+	{"flate-compress", reporting.MegabytesPerSecond, float64(len(integerCorpus)), 60, compressTest(flateWriter)},
+	{"flate-decompress", reporting.MegabytesPerSecond, float64(len(integerCorpus)), 200, decompressTest(flateWriter, flateReader)},
+	{"gzip-compress", reporting.MegabytesPerSecond, float64(len(integerCorpus)), 60, compressTest(gzipWriter)},
+	{"gzip-decompress", reporting.MegabytesPerSecond, float64(len(integerCorpus)), 200, decompressTest(gzipWriter, gzipReader)},
+	{"sort", reporting.OperationsPerSecond, integerSortLength, 5_000_000, sortTest},
+	{"big-mul", reporting.OperationsPerSecond, 1, 2000, bigMulTest},
+	{"crc32", reporting.MegabytesPerSecond, float64(len(integerCorpus)), 20000, crc32Test},
+	{"fnv", reporting.MegabytesPerSecond, float64(len(integerCorpus)), 500, fnvTest},
+}
+
+// integerBenchmark runs every integer test on one goroutine and returns their results and the combined score.
+func integerBenchmark(ctx context.Context) (Results, error) {
+	results := Results{}
+	var logSum float64
+
+	for _, test := range integerTests {
+		if ctx.Err() != nil {
+			return results, nil
+		}
+
+		perSecond, err := opsPerSecond(test.setup, 1, integerDuration)
+		if err != nil {
+			return results, fmt.Errorf("%v: %w", test.name, err)
+		}
+		result := perSecond * test.work
+		if test.unit == reporting.MegabytesPerSecond {
+			result /= 1e6
+		}
+
+		results["integer-"+test.name] = reporting.BenchmarkReport{
+			Result: result,
+			Unit:   test.unit,
+		}
+		logSum += math.Log(result / test.reference)
+	}
+
+	results["integer-score"] = reporting.BenchmarkReport{
+		Result: 1000 * math.Exp(logSum/float64(len(integerTests))),
+		Unit:   reporting.Points,
+	}
+
+	return results, nil
+}
@@ -0,0 +1,119 @@
+package benchmarks
+
+import (
+	"cloud-z/reporting"
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+/*
+   Random 4K storage benchmark.
+
+   A number of goroutines equal to the queue depth each keep one synchronous
+   4KB request in flight at a random offset of a preallocated file. Every
+   request is timed so we get latency percentiles and not just IOPS.
+*/
+
+const iopsBlockSize = 4096
+const iopsPrefillBlockSize = 1024 * 1024
+
+type iopsMode struct {
+	name string
+	// chance of a request being a read
+	readRatio float64
+}
+
+var iopsModes = []iopsMode{
+	{"read", 1},
+	{"write", 0},
+	{"mixed", 0.7},
+}
+
+// iopsRun runs one mode at one queue depth and returns IOPS with the latency distribution.
+func iopsRun(ctx context.Context, path string, size int64, mode iopsMode, queueDepth int, duration time.Duration) (reporting.BenchmarkReport, error) {
+	f, _, err := openDirect(path, os.O_RDWR)
+	if err != nil {
+		return reporting.BenchmarkReport{}, err
+	}
+	defer f.Close()
+
+	blocks := size / iopsBlockSize
+	workerLatencies := make([][]time.Duration, queueDepth)
+	workerErrors := make([]error, queueDepth)
+	deadline := time.Now().Add(duration)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < queueDepth; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			random := rand.New(rand.NewSource(int64(w)))
+			buffer := alignedBuffer(iopsBlockSize)
+			// workers count from their own range so their blocks don't repeat each other's either
+			counter := uint64(w) << 48
+			var latencies []time.Duration
+			for time.Now().Before(deadline) && ctx.Err() == nil {
+				offset := random.Int63n(blocks) * iopsBlockSize
+				requestStart := time.Now()
+				var err error
+				if random.Float64() < mode.readRatio {
+					_, err = f.ReadAt(buffer, offset)
+				} else {
+					counter = stampBlocks(buffer, counter)
+					_, err = f.WriteAt(buffer, offset)
+				}
+				if err != nil {
+					workerErrors[w] = err
+					return
+				}
+				latencies = append(latencies, time.Since(requestStart))
+			}
+			workerLatencies[w] = latencies
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	if ctx.Err() != nil {
+		return reporting.BenchmarkReport{}, ctx.Err()
+	}
+
+	var latencies []time.Duration
+	for w := range workerLatencies {
+		if workerErrors[w] != nil {
+			return reporting.BenchmarkReport{}, workerErrors[w]
+		}
+		latencies = append(latencies, workerLatencies[w]...)
+	}
+
+	return reporting.BenchmarkReport{
+		Result:       float64(len(latencies)) / elapsed.Seconds(),
+		Unit:         reporting.OperationsPerSecond,
+		Distribution: reporting.NewLatencyDistribution(latencies),
+	}, nil
+}
+
+// storageRandom runs random 4KB reads, writes and a 70/30 mix at every queue depth.
+func storageRandom(ctx context.Context, path string, target reporting.StorageTargetReport, options Options) (map[string]reporting.BenchmarkReport, error) {
+	size := storageFileSize(target, options.StorageSize, iopsPrefillBlockSize)
+	if _, _, err := sequentialWrite(ctx, path, size, alignedBuffer(iopsPrefillBlockSize)); err != nil {
+		return nil, err
+	}
+
+	results := map[string]reporting.BenchmarkReport{}
+	for _, queueDepth := range options.StorageQueueDepths {
+		for _, mode := range iopsModes {
+			result, err := iopsRun(ctx, path, size, mode, queueDepth, options.StorageDuration)
+			if err != nil {
+				return results, err
+			}
+			results[fmt.Sprintf("storage-rand-%v-qd%v", mode.name, queueDepth)] = result
+		}
+	}
+
+	return results, nil
+}
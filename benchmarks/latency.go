@@ -0,0 +1,164 @@
+package benchmarks
+
+import (
+	"cloud-z/reporting"
+	"math"
+	"math/rand"
+	"time"
+)
+
+/*
+   Memory latency benchmark using randomized pointer chasing.
+
+   Every working set size is filled with a single random cycle of cache line
+   sized hops, so each load depends on the previous one and the prefetchers
+   can't guess the next address. The time per hop is the load-to-use latency
+   of whatever level of the hierarchy the working set fits in.
+*/
+
+const latencyMinSize = 4 * 1024
+const latencyMinMaxSize = 64 * 1024 * 1024
+const latencyAccesses = 1 << 21
+
+// latencySink keeps the compiler from optimizing the chase away.
+var latencySink uint64
+
+func latencySizes(maxSize int, lineSize int) []int {
+	var sizes []int
+	for size := latencyMinSize; size <= maxSize; size *= 2 {
+		sizes = append(sizes, size)
+		if mid := size / 2 * 3 / lineSize * lineSize; mid <= maxSize {
+			sizes = append(sizes, mid)
+		}
+	}
+	return sizes
+}
+
+func latencyChase(buffer []uint64, accesses int) {
+	p := uint64(0)
+	for i := 0; i < accesses; i++ {
+		p = buffer[p]
+	}
+	latencySink += p
+}
+
+// latencyMeasure links the first size bytes of buffer into one random cycle and returns nanoseconds per hop.
+func latencyMeasure(buffer []uint64, perm []int32, random *rand.Rand, size int, lineSize int) float64 {
+	stride := lineSize / 8
+	lines := size / lineSize
+
+	// Sattolo's algorithm gives a random permutation that is a single cycle
+	for i := 0; i < lines; i++ {
+		perm[i] = int32(i)
+	}
+	for i := lines - 1; i > 0; i-- {
+		j := random.Intn(i)
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+	for i := 0; i < lines; i++ {
+		buffer[i*stride] = uint64(int(perm[i]) * stride)
+	}
+
+	warmup := lines
+	if warmup > latencyAccesses {
+		warmup = latencyAccesses
+	}
+	latencyChase(buffer, warmup)
+
+	start := time.Now()
+	latencyChase(buffer, latencyAccesses)
+	return float64(time.Since(start).Nanoseconds()) / latencyAccesses
+}
+
+// inferCacheBoundaries finds the working set sizes after which latency jumps to the next level. Every point is
+// compared with the point an octave below it, so the slow creep from TLB misses inside a level is ignored.
+func inferCacheBoundaries(curve []reporting.LatencyPointReport) []int {
+	var boundaries []int
+
+	inTransition := false
+	for i := 2; i < len(curve); i++ {
+		latency := curve[i].Nanoseconds
+		if inTransition {
+			// wait for the curve to flatten before looking for the next level
+			if latency < curve[i-1].Nanoseconds*1.15 {
+				inTransition = false
+			}
+			continue
+		}
+		if latency > curve[i-2].Nanoseconds*1.5 {
+			boundaries = append(boundaries, curve[i-1].Size)
+			inTransition = true
+		}
+	}
+
+	return boundaries
+}
+
+func cacheLevels(report *reporting.Report, boundaries []int) []reporting.CacheLevelReport {
+	levels := []reporting.CacheLevelReport{
+		{Level: "L1 data", CpuidSize: report.CPU.CacheL1Data},
+		{Level: "L2", CpuidSize: report.CPU.CacheL2},
+		{Level: "L3", CpuidSize: report.CPU.CacheL3},
+	}
+
+	for i := range levels {
+		if i < len(boundaries) {
+			levels[i].MeasuredSize = boundaries[i]
+		}
+
+		// sizes are sampled at 1.5x steps, so anything within 2x is considered a match
+		cpuidSize := levels[i].CpuidSize
+		measuredSize := levels[i].MeasuredSize
+		levels[i].Mismatch = cpuidSize <= 0 || measuredSize == 0 || measuredSize > cpuidSize*2 || measuredSize*2 < cpuidSize
+	}
+
+	return levels
+}
+
+// memoryLatency sweeps working set sizes from 4KB to several times L3 and fills report.MemoryLatency.
+// It returns the latency of the largest working set, which is main memory latency. When it runs repeatedly the curve
+// keeps the lowest latency of every size, as noise only ever adds latency.
+func memoryLatency(report *reporting.Report) float64 {
+	lineSize := report.CPU.CacheLine
+	if lineSize < 8 {
+		lineSize = 64
+	}
+
+	maxSize := report.CPU.CacheL3 * 4
+	if maxSize < latencyMinMaxSize {
+		maxSize = latencyMinMaxSize
+	}
+	if available := int(report.Memory.Available / 4); available > 0 && maxSize > available {
+		maxSize = available
+	}
+
+	sizes := latencySizes(maxSize, lineSize)
+	if len(sizes) == 0 {
+		return 0
+	}
+	largest := sizes[len(sizes)-1]
+
+	buffer := make([]uint64, largest/8)
+	perm := make([]int32, largest/lineSize)
+	random := rand.New(rand.NewSource(1))
+
+	previous := report.MemoryLatency.Curve
+	curve := make([]reporting.LatencyPointReport, len(sizes))
+	for i, size := range sizes {
+		curve[i] = reporting.LatencyPointReport{
+			Size:        size,
+			Nanoseconds: latencyMeasure(buffer, perm, random, size, lineSize),
+		}
+	}
+	latency := curve[len(curve)-1].Nanoseconds
+
+	if len(previous) == len(curve) {
+		for i := range curve {
+			curve[i].Nanoseconds = math.Min(curve[i].Nanoseconds, previous[i].Nanoseconds)
+		}
+	}
+	report.MemoryLatency.Curve = curve
+	report.MemoryLatency.CacheLevels = cacheLevels(report, inferCacheBoundaries(curve))
+
+	return latency
+}
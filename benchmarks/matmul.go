@@ -0,0 +1,223 @@
+package benchmarks
+
+import (
+	"cloud-z/reporting"
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"time"
+)
+
+/*
+   Matrix multiplication.
+
+   Multiplies square float32 and float64 matrices, the core of most ML
+   preprocessing, and reports GFLOPS on one goroutine and on one goroutine
+   per logical core. The multiplication is blocked so the working set stays
+   in cache, and the inner kernel updates a 4 row block of the result at a
+   time. Go doesn't vectorize loops, so the kernel is written in assembly
+   with AVX2 and FMA on amd64 and with NEON on arm64, and in plain Go
+   everywhere else. Results name the kernel that ran, and the plain Go one
+   is reported as scalar because it doesn't use vector instructions at all.
+
+   The result is compared with a theoretical peak of two FMA units per
+   physical core at the boost frequency, as wide as the vectors of the
+   kernel that ran. It's the peak of that kernel rather than of the CPU,
+   which can be higher with AVX-512 or SVE.
+*/
+
+const matmulDuration = time.Second
+const matmulSize = 512
+const matmulBlockK = 256
+const matmulBlockN = 64
+const matmulRows = 4
+
+type matmulFloat interface {
+	float32 | float64
+}
+
+// matmulKernel adds A[4 x k] * B[k x width] to C[4 x width]. Rows of every matrix are lda, ldb and ldc values apart.
+type matmulKernel[T matmulFloat] func(a []T, lda int, b []T, ldb int, c []T, ldc int, k int)
+
+// matmulKernelGo returns a plain Go kernel for blocks width columns wide.
+func matmulKernelGo[T matmulFloat](width int) matmulKernel[T] {
+	return func(a []T, lda int, b []T, ldb int, c []T, ldc int, k int) {
+		for row := 0; row < matmulRows; row++ {
+			cRow := c[row*ldc : row*ldc+width]
+			for p := 0; p < k; p++ {
+				value := a[row*lda+p]
+				bRow := b[p*ldb : p*ldb+width]
+				for j := range cRow {
+					cRow[j] += value * bRow[j]
+				}
+			}
+		}
+	}
+}
+
+// matmulBlocked sets c to a * b where all of them are n x n. n must be a multiple of the block sizes and width.
+func matmulBlocked[T matmulFloat](kernel matmulKernel[T], width int, a, b, c []T, n int) {
+	for i := range c {
+		c[i] = 0
+	}
+
+	for kk := 0; kk < n; kk += matmulBlockK {
+		for jj := 0; jj < n; jj += matmulBlockN {
+			for i := 0; i < n; i += matmulRows {
+				for j := jj; j < jj+matmulBlockN; j += width {
+					kernel(a[i*n+kk:], n, b[kk*n+j:], n, c[i*n+j:], n, matmulBlockK)
+				}
+			}
+		}
+	}
+}
+
+func matmulRandom[T matmulFloat](random *rand.Rand, n int) []T {
+	matrix := make([]T, n*n)
+	for i := range matrix {
+		matrix[i] = T(random.Float64()*2 - 1)
+	}
+	return matrix
+}
+
+// matmulVerify compares some values of c with a naive float64 dot product.
+func matmulVerify[T matmulFloat](a, b, c []T, n int, tolerance float64) error {
+	random := rand.New(rand.NewSource(2))
+	var mismatches []string
+	for sample := 0; sample < 64; sample++ {
+		i := random.Intn(n)
+		j := random.Intn(n)
+		var want, magnitude float64
+		for p := 0; p < n; p++ {
+			product := float64(a[i*n+p]) * float64(b[p*n+j])
+			want += product
+			magnitude += math.Abs(product)
+		}
+		if got := float64(c[i*n+j]); math.Abs(got-want) > tolerance*magnitude {
+			mismatches = append(mismatches, fmt.Sprintf("C[%v][%v] expected %v but got %v", i, j, want, got))
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return &accuracyError{mismatches: mismatches}
+	}
+	return nil
+}
+
+// matmulGFLOPS multiplies matrices on threads goroutines and returns their combined GFLOPS.
+func matmulGFLOPS[T matmulFloat](kernel matmulKernel[T], width int, a, b []T, n int, threads int) (float64, error) {
+	setup := func() (func(), error) {
+		c := make([]T, n*n)
+		return func() {
+			matmulBlocked(kernel, width, a, b, c, n)
+		}, nil
+	}
+
+	perSecond, err := opsPerSecond(setup, threads, matmulDuration)
+	if err != nil {
+		return 0, err
+	}
+	return perSecond * 2 * float64(n*n*n) / 1e9, nil
+}
+
+// matmulVectorBits is the vector width of every assembly kernel, the scalar kernel handles one value at a time
+var matmulVectorBits = map[string]int{
+	"avx2": 256,
+	"neon": 128,
+}
+
+// matmulPeak returns the theoretical GFLOPS of a kernel on cores, or 0 if the frequency is unknown.
+func matmulPeak(report *reporting.Report, cores int, kernelName string, valueBits int) float64 {
+	ghz := float64(report.CPU.BoostFrequency) / 1e9
+	if ghz <= 0 {
+		ghz = float64(report.CPU.MHz) / 1000
+	}
+	if ghz <= 0 {
+		// SMBIOS is the last resort because hypervisors often make the speed up
+		for _, processor := range report.Platform.Processors {
+			ghz = math.Max(ghz, float64(processor.MaxSpeed)/1000)
+		}
+	}
+	if ghz <= 0 {
+		return 0
+	}
+
+	vectorBits, ok := matmulVectorBits[kernelName]
+	if !ok {
+		vectorBits = valueBits
+	}
+
+	// two FMA units, each doing a multiply and an add on every lane
+	return float64(cores) * ghz * float64(vectorBits/valueBits) * 2 * 2
+}
+
+// matmulRun verifies the kernel and measures single and multi goroutine GFLOPS.
+func matmulRun[T matmulFloat](ctx context.Context, report *reporting.Report, results Results, name string, kernel matmulKernel[T], width int, kernelName string, features []string, valueBits int, tolerance float64) error {
+	random := rand.New(rand.NewSource(1))
+	a := matmulRandom[T](random, matmulSize)
+	b := matmulRandom[T](random, matmulSize)
+	c := make([]T, matmulSize*matmulSize)
+	matmulBlocked(kernel, width, a, b, c, matmulSize)
+	if err := matmulVerify(a, b, c, matmulSize, tolerance); err != nil {
+		return fmt.Errorf("%v: %w", name, err)
+	}
+
+	physical := report.CPU.PhysicalCores
+	if physical <= 0 || physical > runtime.NumCPU() {
+		physical = runtime.NumCPU()
+	}
+
+	threadCounts := []struct {
+		name    string
+		threads int
+		cores   int
+	}{
+		{"single", 1, 1},
+		{"multi", runtime.NumCPU(), physical},
+	}
+
+	for _, threadCount := range threadCounts {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		gflops, err := matmulGFLOPS(kernel, width, a, b, matmulSize, threadCount.threads)
+		if err != nil {
+			return fmt.Errorf("%v: %v", name, err)
+		}
+
+		results[fmt.Sprintf("matmul-%v-%v", name, threadCount.name)] = reporting.BenchmarkReport{
+			Result:      gflops,
+			Unit:        reporting.GFLOPS,
+			Peak:        matmulPeak(report, threadCount.cores, kernelName, valueBits),
+			Kernel:      kernelName,
+			CPUFeatures: features,
+		}
+	}
+
+	return nil
+}
+
+func matmulBenchmark(ctx context.Context, report *reporting.Report) (Results, error) {
+	results := Results{}
+
+	kernel32, kernel64, kernelName, features := matmulKernels(report)
+	width32, width64 := 16, 8
+	if kernel32 == nil {
+		kernel32 = matmulKernelGo[float32](width32)
+		kernel64 = matmulKernelGo[float64](width64)
+		kernelName = "scalar"
+		features = []string{}
+	}
+
+	if err := matmulRun(ctx, report, results, "f32", kernel32, width32, kernelName, features, 32, 1e-4); err != nil {
+		return results, err
+	}
+	if err := matmulRun(ctx, report, results, "f64", kernel64, width64, kernelName, features, 64, 1e-12); err != nil {
+		return results, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,19 @@
+package benchmarks
+
+import "cloud-z/reporting"
+
+//go:noescape
+func matmulKernel32AVX2(a []float32, lda int, b []float32, ldb int, c []float32, ldc int, k int)
+
+//go:noescape
+func matmulKernel64AVX2(a []float64, lda int, b []float64, ldb int, c []float64, ldc int, k int)
+
+// matmulKernels returns assembly kernels with 16 float32 or 8 float64 columns, their name and the CPU features they
+// use, or nil if the CPU doesn't have them.
+func matmulKernels(report *reporting.Report) (matmulKernel[float32], matmulKernel[float64], string, []string) {
+	features := cpuFeatures(report, []string{"AVX2", "FMA3"})
+	if len(features) != 2 {
+		return nil, nil, "", nil
+	}
+	return matmulKernel32AVX2, matmulKernel64AVX2, "avx2", features
+}
@@ -0,0 +1,131 @@
+#include "textflag.h"
+
+// Both kernels keep a 4 row block of C in Y0-Y7, two registers per row, and
+// for every k broadcast one value from each row of A and multiply it with a
+// row of B.
+
+// func matmulKernel32AVX2(a []float32, lda int, b []float32, ldb int, c []float32, ldc int, k int)
+TEXT ·matmulKernel32AVX2(SB), NOSPLIT, $0-104
+	MOVQ a_base+0(FP), SI
+	MOVQ lda+24(FP), R8
+	SHLQ $2, R8
+	MOVQ b_base+32(FP), DI
+	MOVQ ldb+56(FP), R9
+	SHLQ $2, R9
+	MOVQ c_base+64(FP), R11
+	MOVQ ldc+88(FP), R10
+	SHLQ $2, R10
+	MOVQ k+96(FP), CX
+
+	LEAQ (R11)(R10*1), R12
+	LEAQ (R12)(R10*1), R13
+	LEAQ (R13)(R10*1), R14
+	LEAQ (SI)(R8*1), AX
+	LEAQ (AX)(R8*1), BX
+	LEAQ (BX)(R8*1), DX
+
+	VMOVUPS 0(R11), Y0
+	VMOVUPS 32(R11), Y1
+	VMOVUPS 0(R12), Y2
+	VMOVUPS 32(R12), Y3
+	VMOVUPS 0(R13), Y4
+	VMOVUPS 32(R13), Y5
+	VMOVUPS 0(R14), Y6
+	VMOVUPS 32(R14), Y7
+
+loop32:
+	VMOVUPS      0(DI), Y8
+	VMOVUPS      32(DI), Y9
+	VBROADCASTSS (SI), Y10
+	VFMADD231PS  Y8, Y10, Y0
+	VFMADD231PS  Y9, Y10, Y1
+	VBROADCASTSS (AX), Y11
+	VFMADD231PS  Y8, Y11, Y2
+	VFMADD231PS  Y9, Y11, Y3
+	VBROADCASTSS (BX), Y12
+	VFMADD231PS  Y8, Y12, Y4
+	VFMADD231PS  Y9, Y12, Y5
+	VBROADCASTSS (DX), Y13
+	VFMADD231PS  Y8, Y13, Y6
+	VFMADD231PS  Y9, Y13, Y7
+	ADDQ         $4, SI
+	ADDQ         $4, AX
+	ADDQ         $4, BX
+	ADDQ         $4, DX
+	ADDQ         R9, DI
+	DECQ         CX
+	JNZ          loop32
+
+	VMOVUPS Y0, 0(R11)
+	VMOVUPS Y1, 32(R11)
+	VMOVUPS Y2, 0(R12)
+	VMOVUPS Y3, 32(R12)
+	VMOVUPS Y4, 0(R13)
+	VMOVUPS Y5, 32(R13)
+	VMOVUPS Y6, 0(R14)
+	VMOVUPS Y7, 32(R14)
+	VZEROUPPER
+	RET
+
+// func matmulKernel64AVX2(a []float64, lda int, b []float64, ldb int, c []float64, ldc int, k int)
+TEXT ·matmulKernel64AVX2(SB), NOSPLIT, $0-104
+	MOVQ a_base+0(FP), SI
+	MOVQ lda+24(FP), R8
+	SHLQ $3, R8
+	MOVQ b_base+32(FP), DI
+	MOVQ ldb+56(FP), R9
+	SHLQ $3, R9
+	MOVQ c_base+64(FP), R11
+	MOVQ ldc+88(FP), R10
+	SHLQ $3, R10
+	MOVQ k+96(FP), CX
+
+	LEAQ (R11)(R10*1), R12
+	LEAQ (R12)(R10*1), R13
+	LEAQ (R13)(R10*1), R14
+	LEAQ (SI)(R8*1), AX
+	LEAQ (AX)(R8*1), BX
+	LEAQ (BX)(R8*1), DX
+
+	VMOVUPD 0(R11), Y0
+	VMOVUPD 32(R11), Y1
+	VMOVUPD 0(R12), Y2
+	VMOVUPD 32(R12), Y3
+	VMOVUPD 0(R13), Y4
+	VMOVUPD 32(R13), Y5
+	VMOVUPD 0(R14), Y6
+	VMOVUPD 32(R14), Y7
+
+loop64:
+	VMOVUPD      0(DI), Y8
+	VMOVUPD      32(DI), Y9
+	VBROADCASTSD (SI), Y10
+	VFMADD231PD  Y8, Y10, Y0
+	VFMADD231PD  Y9, Y10, Y1
+	VBROADCASTSD (AX), Y11
+	VFMADD231PD  Y8, Y11, Y2
+	VFMADD231PD  Y9, Y11, Y3
+	VBROADCASTSD (BX), Y12
+	VFMADD231PD  Y8, Y12, Y4
+	VFMADD231PD  Y9, Y12, Y5
+	VBROADCASTSD (DX), Y13
+	VFMADD231PD  Y8, Y13, Y6
+	VFMADD231PD  Y9, Y13, Y7
+	ADDQ         $8, SI
+	ADDQ         $8, AX
+	ADDQ         $8, BX
+	ADDQ         $8, DX
+	ADDQ         R9, DI
+	DECQ         CX
+	JNZ          loop64
+
+	VMOVUPD Y0, 0(R11)
+	VMOVUPD Y1, 32(R11)
+	VMOVUPD Y2, 0(R12)
+	VMOVUPD Y3, 32(R12)
+	VMOVUPD Y4, 0(R13)
+	VMOVUPD Y5, 32(R13)
+	VMOVUPD Y6, 0(R14)
+	VMOVUPD Y7, 32(R14)
+	VZEROUPPER
+	RET
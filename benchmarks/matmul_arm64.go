@@ -0,0 +1,15 @@
+package benchmarks
+
+import "cloud-z/reporting"
+
+//go:noescape
+func matmulKernel32NEON(a []float32, lda int, b []float32, ldb int, c []float32, ldc int, k int)
+
+//go:noescape
+func matmulKernel64NEON(a []float64, lda int, b []float64, ldb int, c []float64, ldc int, k int)
+
+// matmulKernels returns assembly kernels with 16 float32 or 8 float64 columns, their name and the CPU features they
+// use. Every arm64 CPU has NEON.
+func matmulKernels(report *reporting.Report) (matmulKernel[float32], matmulKernel[float64], string, []string) {
+	return matmulKernel32NEON, matmulKernel64NEON, "neon", []string{"ASIMD"}
+}
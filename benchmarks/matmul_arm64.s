@@ -0,0 +1,119 @@
+#include "textflag.h"
+
+// Both kernels keep a 4 row block of C in V0-V15, four registers per row, and
+// for every k broadcast one value from each row of A and multiply it with a
+// row of B.
+
+// func matmulKernel32NEON(a []float32, lda int, b []float32, ldb int, c []float32, ldc int, k int)
+TEXT ·matmulKernel32NEON(SB), NOSPLIT, $0-104
+	MOVD a_base+0(FP), R0
+	MOVD lda+24(FP), R4
+	LSL  $2, R4
+	MOVD b_base+32(FP), R1
+	MOVD ldb+56(FP), R5
+	LSL  $2, R5
+	MOVD c_base+64(FP), R2
+	MOVD ldc+88(FP), R6
+	LSL  $2, R6
+	MOVD k+96(FP), R3
+
+	ADD R4, R0, R7
+	ADD R4, R7, R8
+	ADD R4, R8, R9
+	ADD R6, R2, R10
+	ADD R6, R10, R11
+	ADD R6, R11, R12
+
+	VLD1 (R2), [V0.S4, V1.S4, V2.S4, V3.S4]
+	VLD1 (R10), [V4.S4, V5.S4, V6.S4, V7.S4]
+	VLD1 (R11), [V8.S4, V9.S4, V10.S4, V11.S4]
+	VLD1 (R12), [V12.S4, V13.S4, V14.S4, V15.S4]
+
+loop32:
+	VLD1    (R1), [V16.S4, V17.S4, V18.S4, V19.S4]
+	VLD1R.P 4(R0), [V20.S4]
+	VLD1R.P 4(R7), [V21.S4]
+	VLD1R.P 4(R8), [V22.S4]
+	VLD1R.P 4(R9), [V23.S4]
+	VFMLA   V16.S4, V20.S4, V0.S4
+	VFMLA   V17.S4, V20.S4, V1.S4
+	VFMLA   V18.S4, V20.S4, V2.S4
+	VFMLA   V19.S4, V20.S4, V3.S4
+	VFMLA   V16.S4, V21.S4, V4.S4
+	VFMLA   V17.S4, V21.S4, V5.S4
+	VFMLA   V18.S4, V21.S4, V6.S4
+	VFMLA   V19.S4, V21.S4, V7.S4
+	VFMLA   V16.S4, V22.S4, V8.S4
+	VFMLA   V17.S4, V22.S4, V9.S4
+	VFMLA   V18.S4, V22.S4, V10.S4
+	VFMLA   V19.S4, V22.S4, V11.S4
+	VFMLA   V16.S4, V23.S4, V12.S4
+	VFMLA   V17.S4, V23.S4, V13.S4
+	VFMLA   V18.S4, V23.S4, V14.S4
+	VFMLA   V19.S4, V23.S4, V15.S4
+	ADD     R5, R1
+	SUBS    $1, R3
+	BNE     loop32
+
+	VST1 [V0.S4, V1.S4, V2.S4, V3.S4], (R2)
+	VST1 [V4.S4, V5.S4, V6.S4, V7.S4], (R10)
+	VST1 [V8.S4, V9.S4, V10.S4, V11.S4], (R11)
+	VST1 [V12.S4, V13.S4, V14.S4, V15.S4], (R12)
+	RET
+
+// func matmulKernel64NEON(a []float64, lda int, b []float64, ldb int, c []float64, ldc int, k int)
+TEXT ·matmulKernel64NEON(SB), NOSPLIT, $0-104
+	MOVD a_base+0(FP), R0
+	MOVD lda+24(FP), R4
+	LSL  $3, R4
+	MOVD b_base+32(FP), R1
+	MOVD ldb+56(FP), R5
+	LSL  $3, R5
+	MOVD c_base+64(FP), R2
+	MOVD ldc+88(FP), R6
+	LSL  $3, R6
+	MOVD k+96(FP), R3
+
+	ADD R4, R0, R7
+	ADD R4, R7, R8
+	ADD R4, R8, R9
+	ADD R6, R2, R10
+	ADD R6, R10, R11
+	ADD R6, R11, R12
+
+	VLD1 (R2), [V0.D2, V1.D2, V2.D2, V3.D2]
+	VLD1 (R10), [V4.D2, V5.D2, V6.D2, V7.D2]
+	VLD1 (R11), [V8.D2, V9.D2, V10.D2, V11.D2]
+	VLD1 (R12), [V12.D2, V13.D2, V14.D2, V15.D2]
+
+loop64:
+	VLD1    (R1), [V16.D2, V17.D2, V18.D2, V19.D2]
+	VLD1R.P 8(R0), [V20.D2]
+	VLD1R.P 8(R7), [V21.D2]
+	VLD1R.P 8(R8), [V22.D2]
+	VLD1R.P 8(R9), [V23.D2]
+	VFMLA   V16.D2, V20.D2, V0.D2
+	VFMLA   V17.D2, V20.D2, V1.D2
+	VFMLA   V18.D2, V20.D2, V2.D2
+	VFMLA   V19.D2, V20.D2, V3.D2
+	VFMLA   V16.D2, V21.D2, V4.D2
+	VFMLA   V17.D2, V21.D2, V5.D2
+	VFMLA   V18.D2, V21.D2, V6.D2
+	VFMLA   V19.D2, V21.D2, V7.D2
+	VFMLA   V16.D2, V22.D2, V8.D2
+	VFMLA   V17.D2, V22.D2, V9.D2
+	VFMLA   V18.D2, V22.D2, V10.D2
+	VFMLA   V19.D2, V22.D2, V11.D2
+	VFMLA   V16.D2, V23.D2, V12.D2
+	VFMLA   V17.D2, V23.D2, V13.D2
+	VFMLA   V18.D2, V23.D2, V14.D2
+	VFMLA   V19.D2, V23.D2, V15.D2
+	ADD     R5, R1
+	SUBS    $1, R3
+	BNE     loop64
+
+	VST1 [V0.D2, V1.D2, V2.D2, V3.D2], (R2)
+	VST1 [V4.D2, V5.D2, V6.D2, V7.D2], (R10)
+	VST1 [V8.D2, V9.D2, V10.D2, V11.D2], (R11)
+	VST1 [V12.D2, V13.D2, V14.D2, V15.D2], (R12)
+	RET
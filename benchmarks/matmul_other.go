@@ -0,0 +1,9 @@
+//go:build !amd64 && !arm64
+
+package benchmarks
+
+import "cloud-z/reporting"
+
+func matmulKernels(report *reporting.Report) (matmulKernel[float32], matmulKernel[float64], string, []string) {
+	return nil, nil, "", nil
+}
@@ -0,0 +1,58 @@
+package benchmarks
+
+import (
+	"cloud-z/providers"
+	"cloud-z/reporting"
+	"math/rand"
+	"testing"
+)
+
+func testMatmulKernel[T matmulFloat](t *testing.T, name string, kernel matmulKernel[T], width int, tolerance float64) {
+	t.Helper()
+
+	random := rand.New(rand.NewSource(1))
+	a := matmulRandom[T](random, matmulSize)
+	b := matmulRandom[T](random, matmulSize)
+	c := make([]T, matmulSize*matmulSize)
+	matmulBlocked(kernel, width, a, b, c, matmulSize)
+	if err := matmulVerify(a, b, c, matmulSize, tolerance); err != nil {
+		t.Errorf("%v: %v", name, err)
+	}
+}
+
+func TestMatmulKernels(t *testing.T) {
+	testMatmulKernel(t, "scalar f32", matmulKernelGo[float32](16), 16, 1e-4)
+	testMatmulKernel(t, "scalar f64", matmulKernelGo[float64](8), 8, 1e-12)
+
+	report := &reporting.Report{}
+	providers.GetCPUInfo(report)
+	kernel32, kernel64, kernelName, _ := matmulKernels(report)
+	if kernel32 == nil {
+		t.Log("no assembly kernel for this CPU")
+		return
+	}
+	testMatmulKernel(t, kernelName+" f32", kernel32, 16, 1e-4)
+	testMatmulKernel(t, kernelName+" f64", kernel64, 8, 1e-12)
+}
+
+func TestMatmulPeak(t *testing.T) {
+	report := &reporting.Report{}
+	report.CPU.BoostFrequency = 3e9
+
+	tests := []struct {
+		kernel    string
+		valueBits int
+		want      float64
+	}{
+		{"avx2", 32, 96},
+		{"avx2", 64, 48},
+		{"neon", 32, 48},
+		{"scalar", 64, 12},
+	}
+
+	for _, test := range tests {
+		if got := matmulPeak(report, 1, test.kernel, test.valueBits); got != test.want {
+			t.Errorf("%v with %v bits: expected %v but got %v", test.kernel, test.valueBits, test.want, got)
+		}
+	}
+}
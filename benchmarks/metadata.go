@@ -0,0 +1,69 @@
+package benchmarks
+
+import (
+	"cloud-z/reporting"
+	"context"
+	"fmt"
+	"time"
+)
+
+/*
+   Metadata service latency.
+
+   Makes small requests to the instance metadata service one after the
+   other. Software that reads credentials or instance information often
+   hits metadata on hot paths, and slow or throttled responses show up as
+   high tail latency. Every request gives up after a second, which is
+   already far slower than any metadata service should answer.
+*/
+
+const metadataProbes = 100
+const metadataProbeTimeout = time.Second
+
+// metadataProbe makes one request that gives up after metadataProbeTimeout.
+func metadataProbe(ctx context.Context, probe func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, metadataProbeTimeout)
+	defer cancel()
+	return probe(ctx)
+}
+
+func metadataLatency(ctx context.Context, report *reporting.Report, probe func(context.Context) error) (Results, error) {
+	// the first request opens the connection
+	if err := metadataProbe(ctx, probe); err != nil {
+		return nil, err
+	}
+
+	var latencies []time.Duration
+	var failures int
+	var lastErr error
+	for i := 0; i < metadataProbes && ctx.Err() == nil; i++ {
+		start := time.Now()
+		if err := metadataProbe(ctx, probe); err != nil {
+			if ctx.Err() != nil {
+				// interrupted, not a failure of the metadata service
+				break
+			}
+			failures++
+			lastErr = err
+			continue
+		}
+		latencies = append(latencies, time.Since(start))
+	}
+
+	if failures > 0 {
+		// usually throttling
+		report.AddError(fmt.Sprintf("%v of %v metadata latency probes failed: %v", failures, metadataProbes, lastErr))
+	}
+	if len(latencies) == 0 {
+		return nil, nil
+	}
+
+	distribution := reporting.NewLatencyDistribution(latencies)
+	return Results{
+		"metadata-latency": {
+			Result:       distribution.P50,
+			Unit:         reporting.Microseconds,
+			Distribution: distribution,
+		},
+	}, nil
+}
@@ -0,0 +1,135 @@
+package benchmarks
+
+import (
+	"cloud-z/reporting"
+	"fmt"
+	"math/rand"
+	"sync"
+	"unsafe"
+)
+
+/*
+   NUMA local vs remote memory benchmark.
+
+   Memory is bound to each node in turn, then measured from threads pinned
+   to the CPUs of every node. Memory-only nodes, like CXL memory expanders,
+   get a column but no row. This gives a matrix of measured latency and
+   bandwidth that sits next to the kernel's node distance table.
+*/
+
+func uint64s(memory []byte) []uint64 {
+	return unsafe.Slice((*uint64)(unsafe.Pointer(&memory[0])), len(memory)/8)
+}
+
+func float64s(memory []byte) []float64 {
+	return unsafe.Slice((*float64)(unsafe.Pointer(&memory[0])), len(memory)/8)
+}
+
+// runPinned runs f on a goroutine pinned to the given CPUs and waits for it.
+func runPinned(cpus []int, f func()) error {
+	var wg sync.WaitGroup
+	var err error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err = pinThread(cpus); err != nil {
+			return
+		}
+		f()
+	}()
+	wg.Wait()
+	return err
+}
+
+// numaMeasure measures latency and triad bandwidth from every node with CPUs to memory on memoryNode.
+func numaMeasure(report *reporting.Report, memoryNode reporting.NumaNodeReport, size int, lineSize int) error {
+	buffers := make([][]byte, 4)
+	for i := range buffers {
+		buffer, err := allocOnNode(size, memoryNode.Id)
+		if err != nil {
+			return err
+		}
+		defer freeOnNode(buffer)
+		buffers[i] = buffer
+	}
+
+	chase := uint64s(buffers[0])
+	perm := make([]int32, size/lineSize)
+	random := rand.New(rand.NewSource(1))
+	a, b, c := float64s(buffers[1]), float64s(buffers[2]), float64s(buffers[3])
+	triad := streamKernels[len(streamKernels)-1]
+
+	for i, cpuNode := range report.Numa.Nodes {
+		if len(cpuNode.CPUs) == 0 {
+			continue
+		}
+
+		var latency float64
+		err := runPinned(cpuNode.CPUs, func() {
+			latency = latencyMeasure(chase, perm, random, size, lineSize)
+		})
+		if err != nil {
+			return err
+		}
+
+		cpus := cpuNode.CPUs
+		bandwidth, err := streamRun(triad, a, b, c, len(cpus), func() error {
+			return pinThread(cpus)
+		})
+		if err != nil {
+			return err
+		}
+
+		for j, node := range report.Numa.Nodes {
+			if node.Id == memoryNode.Id {
+				report.Numa.Latency[i][j] = latency
+				report.Numa.Bandwidth[i][j] = bandwidth
+			}
+		}
+	}
+
+	return nil
+}
+
+// numa fills report.Numa with the measured latency in ns and triad bandwidth in GB/s between every pair of
+// nodes. Rows are the node the threads run on and columns are the node the memory is on.
+func numa(report *reporting.Report) error {
+	nodes := report.Numa.Nodes
+	if len(nodes) < 2 {
+		return nil
+	}
+
+	lineSize := report.CPU.CacheLine
+	if lineSize < 8 {
+		lineSize = 64
+	}
+
+	// the latency buffer and the three triad arrays all live on the same node
+	size := report.CPU.CacheL3 * 4
+	if size < latencyMinMaxSize {
+		size = latencyMinMaxSize
+	}
+	if available := int(report.Memory.Available / 16); available > 0 && size > available {
+		size = available
+	}
+	size = size / lineSize * lineSize
+
+	report.Numa.Latency = make([][]float64, len(nodes))
+	report.Numa.Bandwidth = make([][]float64, len(nodes))
+	for i := range nodes {
+		report.Numa.Latency[i] = make([]float64, len(nodes))
+		report.Numa.Bandwidth[i] = make([]float64, len(nodes))
+	}
+
+	for _, node := range nodes {
+		if node.Memory == 0 {
+			// CPU-only nodes have no memory to bind to
+			continue
+		}
+		if err := numaMeasure(report, node, size, lineSize); err != nil {
+			return fmt.Errorf("node %v: %v", node.Id, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package benchmarks
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"runtime"
+	"unsafe"
+)
+
+// pinThread locks the calling goroutine to its OS thread and only lets that thread run on the given CPUs. The
+// goroutine should exit without unlocking so the runtime throws the pinned thread away.
+func pinThread(cpus []int) error {
+	runtime.LockOSThread()
+
+	var set unix.CPUSet
+	set.Zero()
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+
+	return unix.SchedSetaffinity(0, &set)
+}
+
+// mbind modes and flags from linux/mempolicy.h
+const mpolBind = 2
+const mpolMfStrict = 1
+
+// allocOnNode maps fresh memory bound to the NUMA node with mbind and touches it so every page is allocated there. The
+// binding overrides an inherited policy like numactl --interleave, and automatic NUMA balancing can't move pages to
+// the node of the CPUs using them as there is no other node they're allowed on.
+func allocOnNode(size int, node int) ([]byte, error) {
+	memory, err := unix.Mmap(-1, 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANONYMOUS)
+	if err != nil {
+		return nil, err
+	}
+
+	mask := make([]uint64, node/64+1)
+	mask[node/64] |= 1 << (node % 64)
+	// the kernel reads one bit less than maxnode
+	maxNode := len(mask)*64 + 1
+	_, _, errno := unix.Syscall6(unix.SYS_MBIND, uintptr(unsafe.Pointer(&memory[0])), uintptr(size), mpolBind,
+		uintptr(unsafe.Pointer(&mask[0])), uintptr(maxNode), mpolMfStrict)
+	if errno != 0 {
+		unix.Munmap(memory)
+		return nil, fmt.Errorf("mbind: %v", errno)
+	}
+
+	pageSize := os.Getpagesize()
+	for i := 0; i < len(memory); i += pageSize {
+		memory[i] = 0
+	}
+
+	return memory, nil
+}
+
+func freeOnNode(memory []byte) {
+	unix.Munmap(memory)
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package benchmarks
+
+import "errors"
+
+var errNumaNotSupported = errors.New("NUMA benchmarks are only supported on Linux")
+
+func pinThread(cpus []int) error {
+	return errNumaNotSupported
+}
+
+func allocOnNode(size int, node int) ([]byte, error) {
+	return nil, errNumaNotSupported
+}
+
+func freeOnNode(memory []byte) {
+}
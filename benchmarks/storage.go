@@ -0,0 +1,256 @@
+package benchmarks
+
+import (
+	"cloud-z/reporting"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+/*
+   Storage benchmarks.
+
+   All storage benchmarks are opt-in and run on a temporary file in the
+   directory picked with --storage-dir. O_DIRECT is used when the filesystem
+   supports it so the page cache doesn't hide the real device speed. When it
+   doesn't, writes are followed by fsync and the file is dropped from the
+   page cache before reading it back.
+
+   Every benchmark stops when the user interrupts, and the temporary
+   directory is removed on the way out.
+*/
+
+const directAlignment = 4096
+
+// alignedBuffer returns a buffer aligned for O_DIRECT and filled with random data so compressing volumes can't cheat.
+// Every write must go through stampBlocks first or deduplicating volumes will.
+func alignedBuffer(size int) []byte {
+	buffer := make([]byte, size+directAlignment)
+	offset := directAlignment - int(uintptr(unsafe.Pointer(&buffer[0]))%directAlignment)
+	buffer = buffer[offset : offset+size]
+	rand.New(rand.NewSource(1)).Read(buffer)
+	return buffer
+}
+
+// stampBlocks writes counter to the start of every 4KB of buffer so no two written blocks are the same. It returns the
+// counter for the next write.
+func stampBlocks(buffer []byte, counter uint64) uint64 {
+	for offset := 0; offset+8 <= len(buffer); offset += directAlignment {
+		binary.LittleEndian.PutUint64(buffer[offset:], counter)
+		counter++
+	}
+	return counter
+}
+
+// storageTarget finds the mounted filesystem the benchmark directory is on.
+func storageTarget(report *reporting.Report, directory string) (reporting.StorageTargetReport, error) {
+	path, err := filepath.Abs(directory)
+	if err != nil {
+		return reporting.StorageTargetReport{}, err
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return reporting.StorageTargetReport{}, err
+	}
+
+	target := reporting.StorageTargetReport{}
+	targetPath := ""
+	for _, filesystem := range report.Storage.Filesystems {
+		mountPoint := filesystem.Path
+		if path != mountPoint && !strings.HasPrefix(path, strings.TrimSuffix(mountPoint, "/")+"/") {
+			continue
+		}
+		if len(mountPoint) >= len(targetPath) {
+			targetPath = mountPoint
+			target = reporting.StorageTargetReport{
+				MountPoint:  filesystem.MountPoint,
+				Filesystem:  filesystem.Type,
+				BlockDevice: filesystem.BlockDevice,
+				Available:   filesystem.Available,
+			}
+		}
+	}
+
+	return target, nil
+}
+
+// storageFileSize caps the test file size so it never takes more than half of the free space.
+func storageFileSize(target reporting.StorageTargetReport, size int64, blockSize int) int64 {
+	if target.Available > 0 && uint64(size) > target.Available/2 {
+		size = int64(target.Available / 2)
+	}
+	size = size / int64(blockSize) * int64(blockSize)
+	if size < int64(blockSize) {
+		size = int64(blockSize)
+	}
+	return size
+}
+
+func blockSizeName(blockSize int) string {
+	if blockSize >= 1024*1024 && blockSize%(1024*1024) == 0 {
+		return fmt.Sprintf("%vMB", blockSize/1024/1024)
+	}
+	return fmt.Sprintf("%vKB", blockSize/1024)
+}
+
+// sequentialWrite writes size bytes and returns MB/s including the final sync.
+func sequentialWrite(ctx context.Context, path string, size int64, buffer []byte) (float64, bool, error) {
+	f, direct, err := openDirect(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return 0, false, err
+	}
+	defer f.Close()
+
+	counter := uint64(0)
+	start := time.Now()
+	for written := int64(0); written < size; written += int64(len(buffer)) {
+		if ctx.Err() != nil {
+			return 0, direct, ctx.Err()
+		}
+		counter = stampBlocks(buffer, counter)
+		if _, err := f.Write(buffer); err != nil {
+			return 0, direct, err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		return 0, direct, err
+	}
+	elapsed := time.Since(start)
+
+	return float64(size) / elapsed.Seconds() / 1e6, direct, nil
+}
+
+// sequentialRead reads the whole file back and returns MB/s.
+func sequentialRead(ctx context.Context, path string, size int64, buffer []byte) (float64, error) {
+	f, direct, err := openDirect(path, os.O_RDONLY)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if !direct {
+		if err := dropCache(f); err != nil {
+			return 0, err
+		}
+	}
+
+	start := time.Now()
+	for read := int64(0); read < size; {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		n, err := f.Read(buffer)
+		if err != nil {
+			return 0, err
+		}
+		read += int64(n)
+	}
+	elapsed := time.Since(start)
+
+	return float64(size) / elapsed.Seconds() / 1e6, nil
+}
+
+// storageSequential runs sequential write and read for every block size and returns MB/s results.
+func storageSequential(ctx context.Context, path string, target *reporting.StorageTargetReport, options Options) (map[string]float64, error) {
+	results := map[string]float64{}
+	for _, blockSize := range options.StorageBlockSizes {
+		size := storageFileSize(*target, options.StorageSize, blockSize)
+		buffer := alignedBuffer(blockSize)
+
+		write, direct, err := sequentialWrite(ctx, path, size, buffer)
+		if err != nil {
+			return nil, err
+		}
+		read, err := sequentialRead(ctx, path, size, buffer)
+		if err != nil {
+			return nil, err
+		}
+
+		target.DirectIO = direct
+		results["storage-seq-write-"+blockSizeName(blockSize)] = write
+		results["storage-seq-read-"+blockSizeName(blockSize)] = read
+	}
+
+	return results, nil
+}
+
+// storage runs all storage benchmarks in a temporary directory inside options.StorageDirectory. When ctx is cancelled
+// it removes the directory and returns the benchmarks that finished.
+func storage(ctx context.Context, report *reporting.Report, options Options) (Results, error) {
+	target, err := storageTarget(report, options.StorageDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	directory, err := os.MkdirTemp(options.StorageDirectory, "cloud-z-")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(directory)
+	defer func() {
+		report.Storage.BenchmarkTarget = target
+	}()
+
+	path := filepath.Join(directory, "benchmark.tmp")
+	results := Results{}
+
+	// the benchmark that was interrupted has no result or error worth reporting
+	sequential, err := storageSequential(ctx, path, &target, options)
+	if ctx.Err() != nil {
+		return results, nil
+	}
+	if err != nil {
+		report.AddError(fmt.Sprintf("Sequential storage benchmark failed: %v", err))
+	}
+	for name, result := range sequential {
+		results[name] = reporting.BenchmarkReport{
+			Result: result,
+			Unit:   reporting.MegabytesPerSecond,
+		}
+	}
+
+	random, err := storageRandom(ctx, path, target, options)
+	if ctx.Err() != nil {
+		return results, nil
+	}
+	if err != nil {
+		report.AddError(fmt.Sprintf("Random storage benchmark failed: %v", err))
+	}
+	for name, result := range random {
+		results[name] = result
+	}
+
+	durability, err := storageFsync(ctx, report, directory, options)
+	if ctx.Err() != nil {
+		return results, nil
+	}
+	if err != nil {
+		report.AddError(fmt.Sprintf("Storage durability benchmark failed: %v", err))
+	}
+	for name, result := range durability {
+		results[name] = result
+	}
+
+	metadata, err := storageMetadata(ctx, directory, options)
+	if ctx.Err() != nil {
+		return results, nil
+	}
+	if err != nil {
+		report.AddError(fmt.Sprintf("Storage metadata benchmark failed: %v", err))
+	}
+	for name, result := range metadata {
+		results[name] = reporting.BenchmarkReport{
+			Result: result,
+			Unit:   reporting.OperationsPerSecond,
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,33 @@
+package benchmarks
+
+import (
+	"errors"
+	"golang.org/x/sys/unix"
+	"os"
+)
+
+// openDirect opens path with O_DIRECT, falling back to buffered I/O on filesystems like tmpfs that don't support it.
+func openDirect(path string, flag int) (*os.File, bool, error) {
+	f, err := os.OpenFile(path, flag|unix.O_DIRECT, 0600)
+	if err == nil {
+		return f, true, nil
+	}
+	if !errors.Is(err, unix.EINVAL) {
+		return nil, false, err
+	}
+
+	f, err = os.OpenFile(path, flag, 0600)
+	return f, false, err
+}
+
+// dropCache evicts the file from the page cache so reads have to go to the device.
+func dropCache(f *os.File) error {
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return unix.Fadvise(int(f.Fd()), 0, 0, unix.FADV_DONTNEED)
+}
+
+func fdatasync(f *os.File) error {
+	return unix.Fdatasync(int(f.Fd()))
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package benchmarks
+
+import "os"
+
+func openDirect(path string, flag int) (*os.File, bool, error) {
+	f, err := os.OpenFile(path, flag, 0600)
+	return f, false, err
+}
+
+func dropCache(f *os.File) error {
+	return nil
+}
+
+func fdatasync(f *os.File) error {
+	return f.Sync()
+}
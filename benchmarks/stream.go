@@ -0,0 +1,160 @@
+package benchmarks
+
+import (
+	"cloud-z/reporting"
+	"runtime"
+	"sync"
+	"time"
+)
+
+/*
+   STREAM-style memory bandwidth benchmark based on John McCalpin's STREAM.
+   https://www.cs.virginia.edu/stream/ref.html
+
+   Arrays are sized well above the last level cache so every kernel streams
+   from main memory. Each kernel runs a few times and the best run is kept.
+*/
+
+const streamTimes = 5
+const streamScalar = 3.0
+const streamMinArrayBytes = 64 * 1024 * 1024
+
+type streamKernel struct {
+	name string
+	// bytes moved per element for each iteration, counting both reads and writes
+	bytesPerElement int
+	run             func(a, b, c []float64)
+}
+
+var streamKernels = []streamKernel{
+	{"copy", 16, func(a, b, c []float64) {
+		for i := range c {
+			c[i] = a[i]
+		}
+	}},
+	{"scale", 16, func(a, b, c []float64) {
+		for i := range b {
+			b[i] = streamScalar * c[i]
+		}
+	}},
+	{"add", 24, func(a, b, c []float64) {
+		for i := range c {
+			c[i] = a[i] + b[i]
+		}
+	}},
+	{"triad", 24, func(a, b, c []float64) {
+		for i := range a {
+			a[i] = b[i] + streamScalar*c[i]
+		}
+	}},
+}
+
+// streamArrayLength picks the number of elements in each of the three arrays. Each array is at least four times
+// the size of L3 as STREAM requires, but all three arrays together never take more than about a third of the
+// available memory.
+func streamArrayLength(report *reporting.Report) int {
+	arrayBytes := uint64(report.CPU.CacheL3) * 4
+	if arrayBytes < streamMinArrayBytes {
+		arrayBytes = streamMinArrayBytes
+	}
+
+	if maxBytes := report.Memory.Available / 9; maxBytes > 0 && arrayBytes > maxBytes {
+		arrayBytes = maxBytes
+	}
+
+	return int(arrayBytes / 8)
+}
+
+// streamRun runs a kernel split between the given number of goroutines and returns the best bandwidth in GB/s.
+// When pin is set, every goroutine calls it before running the kernel, and the first error it returns stops the run.
+func streamRun(kernel streamKernel, a, b, c []float64, threads int, pin func() error) (float64, error) {
+	chunk := (len(a) + threads - 1) / threads
+	best := time.Duration(0)
+
+	for k := 0; k < streamTimes; k++ {
+		var wg sync.WaitGroup
+		pinErrors := make([]error, threads)
+		start := time.Now()
+		for t := 0; t < threads; t++ {
+			from := t * chunk
+			to := from + chunk
+			if to > len(a) {
+				to = len(a)
+			}
+			if from >= to {
+				continue
+			}
+			wg.Add(1)
+			go func(t, from, to int) {
+				defer wg.Done()
+				if pin != nil {
+					if pinErrors[t] = pin(); pinErrors[t] != nil {
+						return
+					}
+				}
+				kernel.run(a[from:to], b[from:to], c[from:to])
+			}(t, from, to)
+		}
+		wg.Wait()
+		elapsed := time.Since(start)
+		for _, err := range pinErrors {
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		if best == 0 || elapsed < best {
+			best = elapsed
+		}
+	}
+
+	return float64(kernel.bytesPerElement*len(a)) / best.Seconds() / 1e9, nil
+}
+
+func streamInit(a, b, c []float64, threads int) {
+	// touch the memory from all threads so pages are allocated before timing
+	chunk := (len(a) + threads - 1) / threads
+	var wg sync.WaitGroup
+	for t := 0; t < threads; t++ {
+		from := t * chunk
+		to := from + chunk
+		if to > len(a) {
+			to = len(a)
+		}
+		if from >= to {
+			continue
+		}
+		wg.Add(1)
+		go func(from, to int) {
+			defer wg.Done()
+			for i := from; i < to; i++ {
+				a[i] = 1.0
+				b[i] = 2.0
+				c[i] = 0.0
+			}
+		}(from, to)
+	}
+	wg.Wait()
+}
+
+// stream returns the bandwidth of every kernel in GB/s for a single thread and for all logical cores.
+func stream(report *reporting.Report) map[string]float64 {
+	length := streamArrayLength(report)
+	a := make([]float64, length)
+	b := make([]float64, length)
+	c := make([]float64, length)
+
+	threads := runtime.NumCPU()
+	streamInit(a, b, c, threads)
+
+	// only pinning can fail and stream doesn't pin
+	results := map[string]float64{}
+	for _, kernel := range streamKernels {
+		results["stream-"+kernel.name+"-single"], _ = streamRun(kernel, a, b, c, 1, nil)
+	}
+	for _, kernel := range streamKernels {
+		results["stream-"+kernel.name+"-multi"], _ = streamRun(kernel, a, b, c, threads, nil)
+	}
+
+	return results
+}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"cloud-z/netbench"
+	"cloud-z/reporting"
+	"fmt"
+	"github.com/spf13/cobra"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+var netCmd = &cobra.Command{
+	Use:   "net",
+	Short: "Benchmark the network between two instances",
+}
+
+var netServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Wait for `cloud-z net bench` to connect from another instance",
+	Run: func(cmd *cobra.Command, args []string) {
+		listen, _ := cmd.Flags().GetString("listen")
+		meshToken, _ := cmd.Flags().GetString("mesh-token")
+		if meshToken == "" {
+			meshToken = os.Getenv("CLOUD_Z_MESH_TOKEN")
+		}
+
+		report, _ := newReport()
+		server := &netbench.Server{
+			Peer: reporting.PeerReport{
+				Cloud:            report.Cloud,
+				InstanceType:     report.InstanceType,
+				Region:           report.Region,
+				AvailabilityZone: report.AvailabilityZone,
+			},
+			Placement: report.Placement,
+			MeshToken: meshToken,
+		}
+
+		listener, err := net.Listen("tcp", listen)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		// one reader per CPU so the UDP flood measures the network and not a single receive loop
+		packetConns, err := netbench.ListenUDP(listener.Addr().String(), runtime.NumCPU())
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, packetConn := range packetConns {
+			go func(packetConn net.PacketConn) {
+				if err := server.ServeUDP(packetConn); err != nil {
+					_, _ = fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+			}(packetConn)
+		}
+
+		fmt.Println("Listening on", listener.Addr(), "TCP and UDP")
+		if err := server.Serve(listener); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+var netBenchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark the network to an instance running `cloud-z net serve`",
+	Run: func(cmd *cobra.Command, args []string) {
+		options, err := getNetBenchmarkOptions(cmd)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		report, _ := newReport()
+		collectInventory(report)
+		netbench.Benchmark(report, options)
+
+		report.Print(noColor)
+
+		submitReport(cmd, report)
+	},
+}
+
+var netMeshCmd = &cobra.Command{
+	Use:   "mesh",
+	Short: "Benchmark latency and throughput between every pair of instances running `cloud-z net serve`",
+	Run: func(cmd *cobra.Command, args []string) {
+		options := netbench.MeshOptions{}
+		options.Peers, _ = cmd.Flags().GetStringSlice("peers")
+		options.Token, _ = cmd.Flags().GetString("token")
+		if options.Token == "" {
+			options.Token = os.Getenv("CLOUD_Z_MESH_TOKEN")
+		}
+		options.Streams, _ = cmd.Flags().GetInt("streams")
+		options.Duration, _ = cmd.Flags().GetDuration("duration")
+		format, _ := cmd.Flags().GetString("format")
+
+		if len(options.Peers) < 2 {
+			_, _ = fmt.Fprintln(os.Stderr, "at least two --peers are required")
+			os.Exit(1)
+		}
+		if options.Token == "" {
+			_, _ = fmt.Fprintln(os.Stderr, "--token or $CLOUD_Z_MESH_TOKEN is required and must match --mesh-token of every peer")
+			os.Exit(1)
+		}
+		if options.Streams < 1 || options.Streams > netbench.MaxStreams {
+			_, _ = fmt.Fprintf(os.Stderr, "streams %v must be between 1 and %v\n", options.Streams, netbench.MaxStreams)
+			os.Exit(1)
+		}
+		if options.Duration <= 0 || options.Duration > netbench.MaxDuration {
+			_, _ = fmt.Fprintf(os.Stderr, "duration %v must be between 0 and %v\n", options.Duration, netbench.MaxDuration)
+			os.Exit(1)
+		}
+		if format != "table" && format != "json" && format != "csv" {
+			_, _ = fmt.Fprintln(os.Stderr, "format must be table, json or csv")
+			os.Exit(1)
+		}
+
+		result, err := netbench.Mesh(options, os.Stderr)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		switch format {
+		case "json":
+			err = result.WriteJSON(os.Stdout)
+		case "csv":
+			err = result.WriteCSV(os.Stdout)
+		default:
+			result.Print(noColor)
+		}
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+func getNetBenchmarkOptions(cmd *cobra.Command) (netbench.Options, error) {
+	options := netbench.Options{}
+
+	options.Peer, _ = cmd.Flags().GetString("peer")
+	if options.Peer == "" {
+		return options, fmt.Errorf("--peer is required")
+	}
+
+	options.Streams, _ = cmd.Flags().GetIntSlice("streams")
+	for _, streams := range options.Streams {
+		if streams < 1 || streams > netbench.MaxStreams {
+			return options, fmt.Errorf("streams %v must be between 1 and %v", streams, netbench.MaxStreams)
+		}
+	}
+
+	options.Duration, _ = cmd.Flags().GetDuration("duration")
+	if options.Duration <= 0 || options.Duration > netbench.MaxDuration {
+		return options, fmt.Errorf("duration %v must be between 0 and %v", options.Duration, netbench.MaxDuration)
+	}
+
+	direction, _ := cmd.Flags().GetString("direction")
+	switch direction {
+	case "both":
+		options.Send = true
+		options.Receive = true
+	case "send":
+		options.Send = true
+	case "receive":
+		options.Receive = true
+	default:
+		return options, fmt.Errorf("direction must be both, send or receive")
+	}
+
+	options.TLS, _ = cmd.Flags().GetBool("tls")
+
+	return options, nil
+}
+
+func addNetCommands() {
+	netServeCmd.Flags().String("listen", ":"+strconv.Itoa(netbench.DefaultPort), "Address to listen on")
+	netServeCmd.Flags().String("mesh-token", "", "Allow `cloud-z net mesh` coordinators that know this shared secret, also read from $CLOUD_Z_MESH_TOKEN (mesh is disabled without it)")
+
+	netBenchCmd.Flags().String("peer", "", "Address of the instance running `cloud-z net serve` as host or host:port")
+	netBenchCmd.Flags().IntSlice("streams", []int{1, 8}, "Number of parallel TCP streams, UDP flood sockets and connection churn workers to test")
+	netBenchCmd.Flags().Duration("duration", 10*time.Second, "How long to run each test")
+	netBenchCmd.Flags().String("direction", "both", "Test send (to peer), receive (from peer) or both; UDP flood only runs when sending")
+	netBenchCmd.Flags().Bool("tls", false, "Also benchmark TLS 1.3 handshakes per second")
+	netBenchCmd.Flags().BoolP("report", "r", false, "Contribute anonymous report")
+	netBenchCmd.Flags().BoolP("no-report", "n", false, "Do not contribute anonymous report")
+
+	netMeshCmd.Flags().StringSlice("peers", nil, "Addresses of instances running `cloud-z net serve` that can all reach each other")
+	netMeshCmd.Flags().String("token", "", "Shared secret every peer was started with using --mesh-token, also read from $CLOUD_Z_MESH_TOKEN")
+	netMeshCmd.Flags().Int("streams", 8, "Number of parallel TCP streams for each pair")
+	netMeshCmd.Flags().Duration("duration", 10*time.Second, "How long to run each test for each pair")
+	netMeshCmd.Flags().String("format", "table", "Output format: table, json or csv")
+
+	netCmd.AddCommand(netServeCmd)
+	netCmd.AddCommand(netMeshCmd)
+	netCmd.AddCommand(netBenchCmd)
+	rootCmd.AddCommand(netCmd)
+}
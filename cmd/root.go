@@ -4,9 +4,14 @@ import (
 	"cloud-z/benchmarks"
 	"cloud-z/providers"
 	"cloud-z/reporting"
+	"context"
 	"fmt"
+	"github.com/inhies/go-bytesize"
 	"github.com/spf13/cobra"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 )
 
 var (
@@ -18,66 +23,196 @@ var (
 )
 
 var noColor bool = false
+var storageSize = bytesize.GB
 
 var rootCmd = &cobra.Command{
 	Use:     "cloud-z",
 	Short:   "Cloud-Z gathers information on cloud instances",
 	Version: versionString,
 	Run: func(cmd *cobra.Command, args []string) {
-		report := &reporting.Report{
-			CloudZVersion: versionString,
+		if smbiosFile, _ := cmd.Flags().GetString("smbios-file"); smbiosFile != "" {
+			decodeSMBIOSFile(cmd, smbiosFile)
+			return
 		}
 
-		allCloudProviders := []providers.CloudProvider{
-			&providers.AwsProvider{},
-			&providers.GcpProvider{},
-			&providers.AzureProvider{},
+		benchmarkOptions, err := getBenchmarkOptions(cmd)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 
-		detectedCloud := false
-		for _, provider := range allCloudProviders {
-			// TODO detect faster with goroutines?
-			if provider.Detect() {
-				provider.GetData(report)
-				detectedCloud = true
-			}
+		report, provider := newReport()
+		if provider != nil {
+			benchmarkOptions.MetadataProbe = provider.ProbeMetadata
 		}
+		collectInventory(report)
 
-		if !detectedCloud {
-			report.AddError("Unable to detect cloud provider")
+		// the first Ctrl+C stops after the current benchmark, the second one kills us
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+		benchmarks.AllBenchmarks(ctx, report, benchmarkOptions)
+		stop()
+
+		report.Print(noColor)
+
+		submitReport(cmd, report)
+	},
+}
+
+// newReport creates a report with the cloud provider information filled in. It also returns the detected provider or
+// nil if no cloud was detected.
+func newReport() (*reporting.Report, providers.CloudProvider) {
+	report := &reporting.Report{
+		CloudZVersion: versionString,
+	}
+
+	allCloudProviders := []providers.CloudProvider{
+		&providers.AwsProvider{},
+		&providers.GcpProvider{},
+		&providers.AzureProvider{},
+	}
+
+	var detectedCloud providers.CloudProvider
+	for _, provider := range allCloudProviders {
+		// TODO detect faster with goroutines?
+		if provider.Detect() {
+			provider.GetData(report)
+			detectedCloud = provider
 		}
+	}
 
-		providers.GetCPUInfo(report)
-		providers.GetMemoryInfo(report)
-		benchmarks.AllBenchmarks(report)
+	if detectedCloud == nil {
+		report.AddError("Unable to detect cloud provider")
+	}
 
-		report.Print(noColor)
+	return report, detectedCloud
+}
+
+func collectInventory(report *reporting.Report) {
+	providers.GetCPUInfo(report)
+	providers.GetMemoryInfo(report)
+	providers.GetMemoryConfigInfo(report)
+	providers.GetNumaInfo(report)
+	providers.GetStorageInfo(report)
+	providers.GetFilesystemInfo(report)
+	providers.GetNetworkInfo(report)
+}
+
+// decodeSMBIOSFile prints what an SMBIOS table dump decodes to. The dump usually comes from another machine, so
+// nothing is collected from this one, no benchmarks run and the report is never submitted.
+func decodeSMBIOSFile(cmd *cobra.Command, smbiosFile string) {
+	report := &reporting.Report{CloudZVersion: versionString}
+	providers.GetSMBIOSInfo(report, smbiosFile)
+	report.PrintSMBIOS(noColor)
+
+	if b, _ := cmd.Flags().GetBool("report"); b {
+		_, _ = fmt.Fprintln(os.Stderr, "Reports decoded from --smbios-file are never submitted.")
+		os.Exit(1)
+	}
+}
 
-		fmt.Println()
+// submitReport asks the user if they want to submit the report, unless --report or --no-report was used. Reports with
+// failed benchmarks can't be sent, so there's nothing to ask and the exit code is 2 however it was run.
+func submitReport(cmd *cobra.Command, report *reporting.Report) {
+	fmt.Println()
 
-		var submitOrViewOrNo rune
+	if failed := report.FailedBenchmarks(); len(failed) > 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "Benchmarks failed (%v). The report will not be submitted.\n", strings.Join(failed, ", "))
+		os.Exit(2)
+	}
+
+	var submitOrViewOrNo rune
+
+	if b, _ := cmd.Flags().GetBool("report"); b {
+		submitOrViewOrNo = 'y'
+	} else if b, _ := cmd.Flags().GetBool("no-report"); b {
+		submitOrViewOrNo = 'n'
+	} else {
+		submitOrViewOrNo = ask("Would you like to anonymously contribute this data to https://weather.cloudsnorkel.com/? Your IP address may be logged, but instance id and other PII will not be sent.", map[rune]string{'y': "yes", 'n': "no", 'v': "view JSON"}, 'n')
+	}
 
-		if b, _ := cmd.Flags().GetBool("report"); b {
-			submitOrViewOrNo = 'y'
-		} else if b, _ := cmd.Flags().GetBool("no-report"); b {
-			submitOrViewOrNo = 'n'
-		} else {
-			submitOrViewOrNo = ask("Would you like to anonymously contribute this data to https://weather.cloudsnorkel.com/? Your IP address may be logged, but instance id and other PII will not be sent.", map[rune]string{'y': "yes", 'n': "no", 'v': "view JSON"}, 'n')
+	if submitOrViewOrNo == 'v' {
+		report.PrintJson(noColor)
+		submitOrViewOrNo = ask("Ok to submit?", map[rune]string{'y': "yes", 'n': "no"}, 'n')
+	}
+	if submitOrViewOrNo == 'y' {
+		if err := report.Send(); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
 		}
+	}
+}
+
+func getBenchmarkOptions(cmd *cobra.Command) (benchmarks.Options, error) {
+	options := benchmarks.Options{}
+
+	options.Bench, _ = cmd.Flags().GetStringSlice("bench")
+	if err := benchmarks.ValidateSelectors(options.Bench); err != nil {
+		return options, err
+	}
+	options.SkipBench, _ = cmd.Flags().GetStringSlice("skip-bench")
+	if err := benchmarks.ValidateSelectors(options.SkipBench); err != nil {
+		return options, err
+	}
 
-		if submitOrViewOrNo == 'v' {
-			report.PrintJson(noColor)
-			submitOrViewOrNo = ask("Ok to submit?", map[rune]string{'y': "yes", 'n': "no"}, 'n')
+	options.Runs, _ = cmd.Flags().GetInt("runs")
+	if options.Runs < 1 {
+		return options, fmt.Errorf("runs %v must be at least 1", options.Runs)
+	}
+	options.RunBudget, _ = cmd.Flags().GetDuration("run-budget")
+
+	options.StorageDirectory, _ = cmd.Flags().GetString("storage-dir")
+
+	blockSizes, _ := cmd.Flags().GetStringSlice("storage-block-sizes")
+	for _, blockSizeString := range blockSizes {
+		blockSize, err := bytesize.Parse(blockSizeString)
+		if err != nil {
+			return options, fmt.Errorf("invalid storage block size %v: %v", blockSizeString, err)
 		}
-		if submitOrViewOrNo == 'y' {
-			report.Send()
+		if blockSize < 128*bytesize.KB || blockSize > 4*bytesize.MB || int(blockSize)%4096 != 0 {
+			return options, fmt.Errorf("storage block size %v must be a multiple of 4KB between 128KB and 4MB", blockSizeString)
 		}
-	},
+		options.StorageBlockSizes = append(options.StorageBlockSizes, int(blockSize))
+	}
+
+	options.StorageSize = int64(storageSize)
+	options.StorageQueueDepths, _ = cmd.Flags().GetIntSlice("storage-queue-depths")
+	for _, queueDepth := range options.StorageQueueDepths {
+		if queueDepth < 1 {
+			return options, fmt.Errorf("storage queue depth %v must be at least 1", queueDepth)
+		}
+	}
+	options.StorageDuration, _ = cmd.Flags().GetDuration("storage-duration")
+	options.StorageVerify, _ = cmd.Flags().GetBool("storage-verify")
+	options.DNSName, _ = cmd.Flags().GetString("dns-name")
+	options.StorageFiles, _ = cmd.Flags().GetInt("storage-files")
+	if options.StorageFiles < 1 {
+		return options, fmt.Errorf("storage files %v must be at least 1", options.StorageFiles)
+	}
+
+	return options, nil
 }
 
 func Execute() {
 	rootCmd.Flags().BoolP("report", "r", false, "Contribute anonymous report")
 	rootCmd.Flags().BoolP("no-report", "n", false, "Do not contribute anonymous report")
+	rootCmd.Flags().String("smbios-file", "", "Only decode and print an SMBIOS table dump (dmidecode --dump-bin or /sys/firmware/dmi/tables/DMI) without benchmarks or submitting")
+	rootCmd.Flags().StringSlice("bench", nil, "Only run benchmarks with these names or categories (cpu, memory, storage, network)")
+	rootCmd.Flags().StringSlice("skip-bench", nil, "Skip benchmarks with these names or categories")
+	rootCmd.Flags().Int("runs", 5, "How many times to run quick benchmarks like fbench and stream")
+	rootCmd.Flags().Duration("run-budget", time.Minute, "Stop repeating a benchmark after this long even if it ran less than --runs times")
+	rootCmd.Flags().String("storage-dir", "", "Run storage benchmarks on a temporary file in this directory")
+	rootCmd.Flags().StringSlice("storage-block-sizes", []string{"128KB", "1MB", "4MB"}, "Block sizes for sequential storage benchmarks")
+	rootCmd.Flags().Var(&storageSize, "storage-size", "Maximum size of the storage benchmark file")
+	rootCmd.Flags().IntSlice("storage-queue-depths", []int{1, 32}, "Queue depths for random storage benchmarks")
+	rootCmd.Flags().Duration("storage-duration", 10*time.Second, "How long to run each random storage benchmark")
+	rootCmd.Flags().Bool("storage-verify", false, "Read back synced writes bypassing the page cache to catch lost or corrupted writes")
+	rootCmd.Flags().Int("storage-files", 20000, "Number of small files for the storage metadata benchmark")
+	rootCmd.Flags().String("dns-name", "google.com", "Name to look up for DNS resolver latency, in a zone without DNSSEC so random names aren't answered from cache, empty to skip")
+	addNetCommands()
 	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Do not use colors to print results")
 	if err := rootCmd.Execute(); err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err)
@@ -18,7 +18,9 @@ require (
 	github.com/jedib0t/go-pretty/v6 v6.4.4
 	github.com/klauspost/cpuid/v2 v2.2.3
 	github.com/spf13/cobra v1.6.1
+	golang.org/x/crypto v0.6.0
 	golang.org/x/sync v0.1.0
+	golang.org/x/sys v0.5.0
 )
 
 require (
@@ -39,5 +41,4 @@ require (
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/rivo/uniseg v0.4.3 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/sys v0.5.0 // indirect
 )
@@ -1,6 +1,7 @@
 package metadata
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -21,8 +22,8 @@ func GetMetadataHeader(header string) (string, error) {
 
 var UnauthorizedError = errors.New("metadata server returned 401")
 
-func requestMetadata(action string, url string, headerName string, headerValue string) (*http.Response, error) {
-	req, err := http.NewRequest(action, "http://169.254.169.254"+url, nil)
+func requestMetadata(ctx context.Context, action string, url string, headerName string, headerValue string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, action, "http://169.254.169.254"+url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -48,7 +49,7 @@ func requestMetadata(action string, url string, headerName string, headerValue s
 }
 
 func GetMetadataJson(url string, target interface{}, headerName string, headerValue string) error {
-	resp, err := requestMetadata("GET", url, headerName, headerValue)
+	resp, err := requestMetadata(context.Background(), "GET", url, headerName, headerValue)
 	if err != nil {
 		return err
 	}
@@ -58,7 +59,12 @@ func GetMetadataJson(url string, target interface{}, headerName string, headerVa
 }
 
 func GetMetadataText(url string, headerName string, headerValue string) (string, error) {
-	resp, err := requestMetadata("GET", url, headerName, headerValue)
+	return GetMetadataTextContext(context.Background(), url, headerName, headerValue)
+}
+
+// GetMetadataTextContext is GetMetadataText that gives up when ctx is done.
+func GetMetadataTextContext(ctx context.Context, url string, headerName string, headerValue string) (string, error) {
+	resp, err := requestMetadata(ctx, "GET", url, headerName, headerValue)
 	if err != nil {
 		return "", err
 	}
@@ -74,7 +80,12 @@ func GetMetadataText(url string, headerName string, headerValue string) (string,
 }
 
 func PutMetadata(url string, headerName string, headerValue string) (string, error) {
-	resp, err := requestMetadata("PUT", url, headerName, headerValue)
+	return PutMetadataContext(context.Background(), url, headerName, headerValue)
+}
+
+// PutMetadataContext is PutMetadata that gives up when ctx is done.
+func PutMetadataContext(ctx context.Context, url string, headerName string, headerValue string) (string, error) {
+	resp, err := requestMetadata(ctx, "PUT", url, headerName, headerValue)
 	if err != nil {
 		return "", err
 	}
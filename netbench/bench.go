@@ -0,0 +1,172 @@
+package netbench
+
+import (
+	"cloud-z/reporting"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+type Options struct {
+	// Peer is host:port of a server, the port defaults to DefaultPort
+	Peer     string
+	Streams  []int
+	Duration time.Duration
+	// Send measures client to server and Receive measures server to client
+	Send    bool
+	Receive bool
+	// TLS adds TLS 1.3 handshakes to the connection churn benchmarks
+	TLS bool
+}
+
+// PeerAddress adds the default port to a peer address if it doesn't have one.
+func PeerAddress(peer string) string {
+	if _, _, err := net.SplitHostPort(peer); err == nil {
+		return peer
+	}
+	return net.JoinHostPort(peer, strconv.Itoa(DefaultPort))
+}
+
+// Benchmark runs TCP throughput, UDP latency and packet rate, and connection churn benchmarks against options.Peer and
+// adds them to the report.
+func Benchmark(report *reporting.Report, options Options) {
+	client, err := Dial(PeerAddress(options.Peer))
+	if err != nil {
+		report.AddError(fmt.Sprintf("Unable to connect to network benchmark server %v: %v", options.Peer, err))
+		return
+	}
+	defer client.Close()
+
+	directions := []struct {
+		name    string
+		enabled bool
+		reverse bool
+	}{
+		{"send", options.Send, false},
+		{"receive", options.Receive, true},
+	}
+
+	for _, direction := range directions {
+		if !direction.enabled {
+			continue
+		}
+		for _, streams := range options.Streams {
+			result, err := client.TCP(streams, options.Duration, direction.reverse)
+			if err != nil {
+				report.AddError(fmt.Sprintf("TCP %v benchmark with %v streams failed: %v", direction.name, streams, err))
+				continue
+			}
+
+			peer := client.Peer
+			report.AddBenchmark(fmt.Sprintf("net-tcp-%v-p%v", direction.name, streams), reporting.BenchmarkReport{
+				Version:  1,
+				Category: reporting.Network,
+				Result:   result.Aggregate,
+				Unit:     reporting.GigabitsPerSecond,
+				Streams:  result.Streams,
+				Peer:     &peer,
+			})
+		}
+	}
+
+	udpPing(report, client, options)
+	if options.Send {
+		udpFlood(report, client, options)
+	}
+	churn(report, client, options)
+}
+
+func udpPing(report *reporting.Report, client *Client, options Options) {
+	result, err := client.UDPPing(options.Duration)
+	if err != nil {
+		report.AddError(fmt.Sprintf("UDP ping benchmark failed: %v", err))
+		return
+	}
+
+	peer := client.Peer
+	distribution := reporting.NewLatencyDistribution(result.Latencies)
+	report.AddBenchmark("net-udp-latency", reporting.BenchmarkReport{
+		Version:      1,
+		Category:     reporting.Network,
+		Result:       distribution.P50,
+		Unit:         reporting.Microseconds,
+		Distribution: distribution,
+		Peer:         &peer,
+	})
+	report.AddBenchmark("net-udp-jitter", reporting.BenchmarkReport{
+		Version:  1,
+		Category: reporting.Network,
+		Result:   float64(result.Jitter.Nanoseconds()) / 1000,
+		Unit:     reporting.Microseconds,
+		Peer:     &peer,
+	})
+	report.AddBenchmark("net-udp-ping-loss", reporting.BenchmarkReport{
+		Version:  1,
+		Category: reporting.Network,
+		Result:   100 * float64(result.Lost) / float64(result.Sent),
+		Unit:     reporting.Percent,
+		Peer:     &peer,
+	})
+}
+
+func udpFlood(report *reporting.Report, client *Client, options Options) {
+	for _, streams := range options.Streams {
+		result, err := client.UDPFlood(streams, options.Duration)
+		if err != nil {
+			report.AddError(fmt.Sprintf("UDP flood benchmark with %v streams failed: %v", streams, err))
+			continue
+		}
+
+		peer := client.Peer
+		name := fmt.Sprintf("net-udp-flood-p%v", streams)
+		report.AddBenchmark(name, reporting.BenchmarkReport{
+			Version:  1,
+			Category: reporting.Network,
+			Result:   result.PacketsPerSecond(),
+			Unit:     reporting.PacketsPerSecond,
+			Peer:     &peer,
+		})
+		report.AddBenchmark(name+"-loss", reporting.BenchmarkReport{
+			Version:  1,
+			Category: reporting.Network,
+			Result:   result.Loss(),
+			Unit:     reporting.Percent,
+			Peer:     &peer,
+		})
+	}
+}
+
+func churn(report *reporting.Report, client *Client, options Options) {
+	tests := []struct {
+		name    string
+		enabled bool
+		tls     bool
+	}{
+		{"net-tcp-connect", true, false},
+		{"net-tls-handshake", options.TLS, true},
+	}
+
+	for _, test := range tests {
+		if !test.enabled {
+			continue
+		}
+		for _, workers := range options.Streams {
+			result, err := client.Churn(workers, options.Duration, test.tls)
+			if err != nil {
+				report.AddError(fmt.Sprintf("Connection churn benchmark %v with %v workers failed: %v", test.name, workers, err))
+				continue
+			}
+
+			peer := client.Peer
+			report.AddBenchmark(fmt.Sprintf("%v-p%v", test.name, workers), reporting.BenchmarkReport{
+				Version:      1,
+				Category:     reporting.Network,
+				Result:       result.PerSecond(),
+				Unit:         reporting.OperationsPerSecond,
+				Distribution: reporting.NewLatencyDistribution(result.Latencies),
+				Peer:         &peer,
+			})
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package netbench
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+)
+
+/*
+   Connection churn benchmarks open a new connection for every request.
+   Each connection sends its header, waits for a single byte from the
+   server and is done. The server closes first so TIME_WAIT sockets pile up
+   on the server side and the client doesn't run out of ephemeral ports.
+
+   TLS connections go to the same port. The server tells them apart by the
+   first byte which is always 0x16 for a TLS handshake and '{' for plain
+   connections. The certificate is self-signed and generated when the
+   server starts so only the handshake cost is measured.
+*/
+
+const tlsHandshakeRecord = 0x16
+
+type ChurnResult struct {
+	Connections int
+	Seconds     float64
+	Latencies   []time.Duration
+}
+
+func (r ChurnResult) PerSecond() float64 {
+	if r.Seconds <= 0 {
+		return 0
+	}
+	return float64(r.Connections) / r.Seconds
+}
+
+// bufferedConn reads from a reader that already peeked into the connection.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "cloud-z"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{certificate}, PrivateKey: key}, nil
+}
+
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	s.tlsOnce.Do(func() {
+		certificate, err := selfSignedCertificate()
+		if err != nil {
+			s.tlsErr = err
+			return
+		}
+		s.tls = &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			MinVersion:   tls.VersionTLS13,
+		}
+	})
+	return s.tls, s.tlsErr
+}
+
+// churn answers a single connection of a churn test.
+func (s *Server) churn(conn net.Conn, session string) {
+	defer conn.Close()
+
+	s.sessionsMutex.Lock()
+	ok := s.churns[session]
+	s.sessionsMutex.Unlock()
+
+	if ok {
+		_, _ = conn.Write([]byte{'k'})
+	}
+}
+
+func (s *Server) churnTest(conn net.Conn, decoder *json.Decoder) message {
+	s.testMutex.Lock()
+	defer s.testMutex.Unlock()
+
+	session := newSessionId()
+	s.sessionsMutex.Lock()
+	if s.churns == nil {
+		s.churns = map[string]bool{}
+	}
+	s.churns[session] = true
+	s.sessionsMutex.Unlock()
+
+	defer func() {
+		s.sessionsMutex.Lock()
+		delete(s.churns, session)
+		s.sessionsMutex.Unlock()
+	}()
+
+	if err := writeMessage(conn, message{Type: messageReady, Session: session}); err != nil {
+		return message{Type: messageResult, Error: err.Error()}
+	}
+	return message{Type: messageResult, Error: errorString(waitForDone(conn, decoder))}
+}
+
+// Churn opens and closes connections from parallel workers for the given duration. With useTLS every connection
+// does a full TLS 1.3 handshake.
+func (c *Client) Churn(workers int, duration time.Duration, useTLS bool) (ChurnResult, error) {
+	ready, err := c.request(message{Type: messageChurn})
+	if err != nil {
+		return ChurnResult{}, err
+	}
+	if ready.Type != messageReady {
+		return ChurnResult{}, fmt.Errorf("unexpected %v message", ready.Type)
+	}
+
+	result, err := c.churn(ready.Session, workers, duration, useTLS)
+
+	_, doneErr := c.request(message{Type: messageDone})
+	if err != nil {
+		return result, err
+	}
+	return result, doneErr
+}
+
+// connectOnce opens one connection and waits for the server to answer and close it.
+func (c *Client) connectOnce(header []byte, tlsConfig *tls.Config) error {
+	conn, err := net.DialTimeout("tcp", c.address, headerTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(headerTimeout))
+
+	if tlsConfig != nil {
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return err
+		}
+		conn = tlsConn
+	}
+
+	if _, err := conn.Write(header); err != nil {
+		return err
+	}
+	answer, err := io.ReadAll(conn)
+	if err != nil {
+		return err
+	}
+	if len(answer) != 1 {
+		return fmt.Errorf("server closed connection without answering")
+	}
+	return nil
+}
+
+func (c *Client) churn(session string, workers int, duration time.Duration, useTLS bool) (ChurnResult, error) {
+	header, err := json.Marshal(message{Type: messageChurnConnection, Session: session})
+	if err != nil {
+		return ChurnResult{}, err
+	}
+	header = append(header, '\n')
+
+	var tlsConfig *tls.Config
+	if useTLS {
+		tlsConfig = &tls.Config{
+			// the server certificate is self-signed and thrown away when it exits
+			InsecureSkipVerify: true,
+			MinVersion:         tls.VersionTLS13,
+		}
+	}
+
+	workerLatencies := make([][]time.Duration, workers)
+	workerErrors := make([]error, workers)
+	deadline := time.Now().Add(duration)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				connectStart := time.Now()
+				if err := c.connectOnce(header, tlsConfig); err != nil {
+					workerErrors[w] = err
+					return
+				}
+				workerLatencies[w] = append(workerLatencies[w], time.Since(connectStart))
+			}
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	result := ChurnResult{Seconds: elapsed.Seconds()}
+	for w := 0; w < workers; w++ {
+		if workerErrors[w] != nil {
+			return result, workerErrors[w]
+		}
+		result.Latencies = append(result.Latencies, workerLatencies[w]...)
+	}
+	result.Connections = len(result.Latencies)
+
+	return result, nil
+}
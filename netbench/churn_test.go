@@ -0,0 +1,85 @@
+package netbench
+
+import (
+	"cloud-z/reporting"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingListener counts accepted connections.
+type countingListener struct {
+	net.Listener
+	accepted int64
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		atomic.AddInt64(&l.accepted, 1)
+	}
+	return conn, err
+}
+
+func TestChurn(t *testing.T) {
+	for _, useTLS := range []bool{false, true} {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		counting := &countingListener{Listener: listener}
+		go (&Server{}).Serve(counting)
+
+		client, err := Dial(listener.Addr().String())
+		if err != nil {
+			listener.Close()
+			t.Fatal(err)
+		}
+
+		result, err := client.Churn(2, 300*time.Millisecond, useTLS)
+		client.Close()
+		listener.Close()
+		if err != nil {
+			t.Fatalf("tls %v: %v", useTLS, err)
+		}
+
+		if result.Connections == 0 {
+			t.Fatalf("tls %v: no connections", useTLS)
+		}
+		if result.Connections != len(result.Latencies) {
+			t.Errorf("tls %v: %v connections but %v latencies", useTLS, result.Connections, len(result.Latencies))
+		}
+		// the server saw every churn connection plus the control channel
+		if accepted := atomic.LoadInt64(&counting.accepted); accepted != int64(result.Connections)+1 {
+			t.Errorf("tls %v: client made %v connections but server accepted %v", useTLS, result.Connections, accepted-1)
+		}
+		if result.PerSecond() <= 0 {
+			t.Errorf("tls %v: %v connections per second", useTLS, result.PerSecond())
+		}
+
+		for _, latency := range result.Latencies {
+			if latency <= 0 || latency > headerTimeout {
+				t.Errorf("tls %v: connection took %v", useTLS, latency)
+				break
+			}
+		}
+
+		distribution := reporting.NewLatencyDistribution(result.Latencies)
+		if !(0 < distribution.P50 && distribution.P50 <= distribution.P99 && distribution.P99 <= distribution.P999 && distribution.P999 <= distribution.Max) {
+			t.Errorf("tls %v: percentiles out of order %+v", useTLS, distribution)
+		}
+		if distribution.Max != float64(result.Latencies[len(result.Latencies)-1].Nanoseconds())/1000 {
+			t.Errorf("tls %v: max %v isn't the slowest connection", useTLS, distribution.Max)
+		}
+	}
+}
+
+func TestChurnUnknownSession(t *testing.T) {
+	client := dialServer(t, startServer(t, &Server{}))
+
+	// connections for a session the server didn't start get no answer
+	if _, err := client.churn("0123456789abcdef0123456789abcdef", 1, 50*time.Millisecond, false); err == nil {
+		t.Error("expected an error for an unknown session")
+	}
+}
@@ -0,0 +1,150 @@
+package netbench
+
+import (
+	"cloud-z/reporting"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+type Client struct {
+	// Peer is what the server reported about itself
+	Peer reporting.PeerReport
+	// Placement is what the server reported about its placement, only when it accepted the mesh token
+	Placement string
+
+	address string
+	conn    net.Conn
+	decoder *json.Decoder
+}
+
+// Dial connects to a server and exchanges versions and instance information.
+func Dial(address string) (*Client, error) {
+	return DialMesh(address, "")
+}
+
+// DialMesh connects to a server like Dial and authenticates with the mesh token the server was started with. The
+// server refuses the connection if the token is wrong or mesh is disabled.
+func DialMesh(address string, token string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", address, headerTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &Client{
+		address: address,
+		conn:    conn,
+		decoder: json.NewDecoder(conn),
+	}
+
+	hello, err := client.request(message{Type: messageHello, Version: ProtocolVersion, Token: token})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if hello.Peer != nil {
+		client.Peer = *hello.Peer
+	}
+	client.Placement = hello.Placement
+
+	return client, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// read waits for the next message from the server. Servers reply right away or after waiting at most headerTimeout for
+// data connections, so anything longer means the server is stuck.
+func (c *Client) read() (message, error) {
+	return c.readWithin(replyTimeout)
+}
+
+func (c *Client) readWithin(timeout time.Duration) (message, error) {
+	var reply message
+	_ = c.conn.SetReadDeadline(time.Now().Add(timeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+	if err := c.decoder.Decode(&reply); err != nil {
+		return reply, fmt.Errorf("control channel: %v", err)
+	}
+	return reply, reply.err()
+}
+
+func (c *Client) request(m message) (message, error) {
+	return c.requestWithin(m, replyTimeout)
+}
+
+// requestWithin sends a request and waits up to timeout for the reply, for requests that run a test on the server.
+func (c *Client) requestWithin(m message, timeout time.Duration) (message, error) {
+	if err := writeMessage(c.conn, m); err != nil {
+		return message{}, fmt.Errorf("control channel: %v", err)
+	}
+	return c.readWithin(timeout)
+}
+
+func (c *Client) openStreams(session string, streams int) ([]net.Conn, error) {
+	var conns []net.Conn
+	for i := 0; i < streams; i++ {
+		conn, err := net.DialTimeout("tcp", c.address, headerTimeout)
+		if err != nil {
+			return conns, err
+		}
+		conns = append(conns, conn)
+		if err := writeMessage(conn, message{Type: messageData, Session: session}); err != nil {
+			return conns, err
+		}
+	}
+	return conns, nil
+}
+
+// TCP measures throughput with parallel streams. Data goes from the client to the server, or from the server to the
+// client when reverse is set.
+func (c *Client) TCP(streams int, duration time.Duration, reverse bool) (TCPResult, error) {
+	ready, err := c.request(message{Type: messageTCP, Streams: streams, Duration: duration, Reverse: reverse})
+	if err != nil {
+		return TCPResult{}, err
+	}
+	if ready.Type != messageReady {
+		return TCPResult{}, fmt.Errorf("unexpected %v message", ready.Type)
+	}
+
+	conns, err := c.openStreams(ready.Session, streams)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	var result TCPResult
+	if err == nil {
+		if reverse {
+			readers := make([]io.Reader, len(conns))
+			for i, conn := range conns {
+				readers[i] = conn
+			}
+			result, err = receiveAll(conns, readers, duration)
+		} else {
+			err = sendAll(conns, duration)
+		}
+	}
+
+	// always wait for the server to finish so the control channel stays in sync
+	reply, replyErr := c.read()
+	if err != nil {
+		return TCPResult{}, err
+	}
+	if replyErr != nil {
+		return TCPResult{}, replyErr
+	}
+
+	if !reverse {
+		if reply.Result == nil {
+			return TCPResult{}, fmt.Errorf("server sent no result")
+		}
+		result = *reply.Result
+	}
+
+	return result, nil
+}
@@ -0,0 +1,268 @@
+package netbench
+
+import (
+	"cloud-z/reporting"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+/*
+   Mesh runs pairwise benchmarks between any number of servers.
+
+   The coordinator connects to every server and asks them one at a time to
+   benchmark one of the others, so only one pair is measured at any moment.
+   Every server connects to the others using the address the coordinator
+   was given, so use addresses all peers can reach like private IPs.
+
+   Mesh is disabled unless the servers are started with a mesh token, and
+   the coordinator must send the same token. A server only benchmarks the
+   peers the coordinator registered on the same connection, and connects
+   to them with its token, so it can't be used to reach anything else.
+   Only authenticated coordinators get the placement group and host.
+*/
+
+type PairResult struct {
+	// median UDP round trip in microseconds
+	Latency float64 `json:"latency"`
+	// UDP jitter in microseconds
+	Jitter float64 `json:"jitter"`
+	// TCP throughput in Gbps
+	Throughput float64 `json:"throughput"`
+}
+
+type MeshPeer struct {
+	Address          string `json:"address"`
+	Cloud            string `json:"cloud"`
+	InstanceType     string `json:"instanceType"`
+	Region           string `json:"region"`
+	AvailabilityZone string `json:"availabilityZone"`
+	Placement        string `json:"placement,omitempty"`
+}
+
+type MeshResult struct {
+	Peers []MeshPeer `json:"peers"`
+	// Latency[i][j] is from peers[i] to peers[j] in microseconds
+	Latency [][]float64 `json:"latency"`
+	// Jitter[i][j] is from peers[i] to peers[j] in microseconds
+	Jitter [][]float64 `json:"jitter"`
+	// Throughput[i][j] is from peers[i] to peers[j] in Gbps
+	Throughput [][]float64 `json:"throughput"`
+	Errors     []string    `json:"errors,omitempty"`
+}
+
+type MeshOptions struct {
+	Peers []string
+	// Token must match the mesh token of every peer
+	Token    string
+	Streams  int
+	Duration time.Duration
+}
+
+// meshPair runs the benchmarks a mesh coordinator asked for against another server.
+func (s *Server) meshPair(request message) message {
+	if request.Streams < 1 || request.Streams > MaxStreams {
+		return message{Type: messageResult, Error: fmt.Sprintf("streams must be between 1 and %v", MaxStreams)}
+	}
+	if request.Duration <= 0 || request.Duration > MaxDuration {
+		return message{Type: messageResult, Error: fmt.Sprintf("duration must be between 0 and %v", MaxDuration)}
+	}
+
+	// the test lock is not taken here as the coordinator never runs two pairs at once and the target server takes
+	// its own lock
+	client, err := DialMesh(request.Target, s.MeshToken)
+	if err != nil {
+		return message{Type: messageResult, Error: err.Error()}
+	}
+	defer client.Close()
+
+	ping, err := client.UDPPing(request.Duration)
+	if err != nil {
+		return message{Type: messageResult, Error: fmt.Sprintf("UDP ping: %v", err)}
+	}
+	throughput, err := client.TCP(request.Streams, request.Duration, false)
+	if err != nil {
+		return message{Type: messageResult, Error: fmt.Sprintf("TCP: %v", err)}
+	}
+
+	return message{Type: messageResult, Pair: &PairResult{
+		Latency:    reporting.NewLatencyDistribution(ping.Latencies).P50,
+		Jitter:     float64(ping.Jitter.Nanoseconds()) / 1000,
+		Throughput: throughput.Aggregate,
+	}}
+}
+
+// MeshPair asks the server to benchmark another server at target.
+func (c *Client) MeshPair(target string, streams int, duration time.Duration) (PairResult, error) {
+	// the server runs a UDP ping and a TCP test before it answers
+	request := message{Type: messageMesh, Target: target, Streams: streams, Duration: duration}
+	reply, err := c.requestWithin(request, 2*duration+2*replyTimeout)
+	if err != nil {
+		return PairResult{}, err
+	}
+	if reply.Pair == nil {
+		return PairResult{}, fmt.Errorf("server sent no result")
+	}
+	return *reply.Pair, nil
+}
+
+func squareMatrix(n int) [][]float64 {
+	matrix := make([][]float64, n)
+	for i := range matrix {
+		matrix[i] = make([]float64, n)
+	}
+	return matrix
+}
+
+// Mesh benchmarks every ordered pair of peers one after the other. Progress is written to progress.
+func Mesh(options MeshOptions, progress io.Writer) (MeshResult, error) {
+	result := MeshResult{}
+
+	var clients []*Client
+	defer func() {
+		for _, client := range clients {
+			client.Close()
+		}
+	}()
+
+	for _, peer := range options.Peers {
+		address := PeerAddress(peer)
+		client, err := DialMesh(address, options.Token)
+		if err != nil {
+			return result, fmt.Errorf("unable to connect to %v: %v", peer, err)
+		}
+		clients = append(clients, client)
+		result.Peers = append(result.Peers, MeshPeer{
+			Address:          address,
+			Cloud:            client.Peer.Cloud,
+			InstanceType:     client.Peer.InstanceType,
+			Region:           client.Peer.Region,
+			AvailabilityZone: client.Peer.AvailabilityZone,
+			Placement:        client.Placement,
+		})
+	}
+
+	var addresses []string
+	for _, peer := range result.Peers {
+		addresses = append(addresses, peer.Address)
+	}
+	for i, client := range clients {
+		if _, err := client.request(message{Type: messageMeshPeers, Peers: addresses}); err != nil {
+			return result, fmt.Errorf("unable to register mesh peers with %v: %v", options.Peers[i], err)
+		}
+	}
+
+	result.Latency = squareMatrix(len(clients))
+	result.Jitter = squareMatrix(len(clients))
+	result.Throughput = squareMatrix(len(clients))
+
+	for i, client := range clients {
+		for j, target := range result.Peers {
+			if i == j {
+				continue
+			}
+
+			_, _ = fmt.Fprintf(progress, "Benchmarking #%v %v -> #%v %v\n", i+1, result.Peers[i].Address, j+1, target.Address)
+			pair, err := client.MeshPair(target.Address, options.Streams, options.Duration)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("#%v -> #%v: %v", i+1, j+1, err))
+				continue
+			}
+
+			result.Latency[i][j] = pair.Latency
+			result.Jitter[i][j] = pair.Jitter
+			result.Throughput[i][j] = pair.Throughput
+		}
+	}
+
+	return result, nil
+}
+
+func (r MeshResult) Print(noColor bool) {
+	peers := table.NewWriter()
+	peers.SetOutputMirror(os.Stdout)
+	peers.SetAllowedRowLength(120)
+	peers.SetTitle("Mesh Peers")
+	peers.AppendHeader(table.Row{"#", "Address", "Cloud", "Instance type", "Availability zone", "Placement"})
+	for i, peer := range r.Peers {
+		peers.AppendRow(table.Row{i + 1, peer.Address, peer.Cloud, peer.InstanceType, peer.AvailabilityZone, peer.Placement})
+	}
+	if !noColor {
+		peers.SetStyle(table.StyleColoredMagentaWhiteOnBlack)
+	}
+	peers.Render()
+
+	matrix := table.NewWriter()
+	matrix.SetOutputMirror(os.Stdout)
+	matrix.SetAllowedRowLength(120)
+	matrix.SetTitle("Mesh")
+	header := table.Row{"From \\ To"}
+	for i := range r.Peers {
+		header = append(header, fmt.Sprintf("#%v", i+1))
+	}
+	matrix.AppendHeader(header)
+	for i := range r.Peers {
+		row := table.Row{fmt.Sprintf("#%v", i+1)}
+		for j := range r.Peers {
+			if i == j {
+				row = append(row, "-")
+				continue
+			}
+			row = append(row, fmt.Sprintf("%.1f us\n%.2f Gbps", r.Latency[i][j], r.Throughput[i][j]))
+		}
+		matrix.AppendRow(row)
+	}
+	if !noColor {
+		matrix.SetStyle(table.StyleColoredMagentaWhiteOnBlack)
+	}
+	matrix.Render()
+
+	for _, err := range r.Errors {
+		fmt.Println(err)
+	}
+}
+
+func (r MeshResult) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteCSV writes one row per source peer with its latency, jitter and throughput to every other peer.
+func (r MeshResult) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"peer", "address", "cloud", "instance type", "availability zone", "placement"}
+	for _, metric := range []string{"latency us", "jitter us", "throughput Gbps"} {
+		for j := range r.Peers {
+			header = append(header, fmt.Sprintf("%v to #%v", metric, j+1))
+		}
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for i, peer := range r.Peers {
+		row := []string{strconv.Itoa(i + 1), peer.Address, peer.Cloud, peer.InstanceType, peer.AvailabilityZone, peer.Placement}
+		for _, matrix := range [][][]float64{r.Latency, r.Jitter, r.Throughput} {
+			for j := range r.Peers {
+				if i == j {
+					row = append(row, "")
+					continue
+				}
+				row = append(row, strconv.FormatFloat(matrix[i][j], 'f', -1, 64))
+			}
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,109 @@
+package netbench
+
+import (
+	"bufio"
+	"cloud-z/reporting"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+/*
+   Network benchmarks between two instances.
+
+   One side runs `cloud-z net serve` and the other connects to it with
+   `cloud-z net bench`. Everything goes over a single TCP port. The first
+   connection is the control channel where the client asks for tests and
+   the server reports back its own instance type and availability zone.
+   Data connections open later and name the test session they belong to
+   in their first line.
+
+   Throughput is always measured on the receiving side, from the first byte
+   to the end of the stream, so connection setup and buffering in the
+   sender don't count.
+*/
+
+const ProtocolVersion = 1
+const DefaultPort = 5201
+
+// limits on what a client can ask a server to do
+const MaxDuration = time.Minute
+const MaxStreams = 128
+
+const headerTimeout = 10 * time.Second
+
+// replyTimeout is how long clients wait for the server to answer on the control channel
+const replyTimeout = 2 * headerTimeout
+
+const (
+	messageHello  = "hello"
+	messageTCP    = "tcp"
+	messageReady  = "ready"
+	messageData   = "data"
+	messageResult = "result"
+	messageDone   = "done"
+
+	messageUDPPing  = "udp-ping"
+	messageUDPFlood = "udp-flood"
+
+	messageChurn           = "churn"
+	messageChurnConnection = "churn-connection"
+
+	messageMesh      = "mesh"
+	messageMeshPeers = "mesh-peers"
+)
+
+type message struct {
+	Type      string                `json:"type"`
+	Version   int                   `json:"version,omitempty"`
+	Peer      *reporting.PeerReport `json:"peer,omitempty"`
+	Placement string                `json:"placement,omitempty"`
+	Token     string                `json:"token,omitempty"`
+	Target    string                `json:"target,omitempty"`
+	Peers     []string              `json:"peers,omitempty"`
+	Session   string                `json:"session,omitempty"`
+	Streams   int                   `json:"streams,omitempty"`
+	Duration  time.Duration         `json:"duration,omitempty"`
+	Reverse   bool                  `json:"reverse,omitempty"`
+	Result    *TCPResult            `json:"result,omitempty"`
+	Flood     *UDPFloodResult       `json:"flood,omitempty"`
+	Pair      *PairResult           `json:"pair,omitempty"`
+	Error     string                `json:"error,omitempty"`
+}
+
+func (m message) err() error {
+	if m.Error != "" {
+		return errors.New(m.Error)
+	}
+	return nil
+}
+
+// readHeader reads the first line of a connection. The reader must be used for the rest of the connection as it may
+// have buffered more than the header.
+func readHeader(conn net.Conn, reader *bufio.Reader) (message, error) {
+	var header message
+
+	_ = conn.SetReadDeadline(time.Now().Add(headerTimeout))
+	line, err := reader.ReadSlice('\n')
+	_ = conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		return header, err
+	}
+
+	if err := json.Unmarshal(line, &header); err != nil {
+		return header, fmt.Errorf("invalid header: %v", err)
+	}
+
+	return header, nil
+}
+
+func writeMessage(conn net.Conn, m message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,296 @@
+package netbench
+
+import (
+	"bufio"
+	"cloud-z/reporting"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+type Server struct {
+	// Peer is what the server tells clients about itself
+	Peer reporting.PeerReport
+	// Placement is only sent to clients that know MeshToken as it has user picked names
+	Placement reporting.PlacementReport
+	// MeshToken enables mesh coordination for clients that send it, mesh is disabled when empty
+	MeshToken string
+
+	// only one test runs at a time so clients don't skew each other's results
+	testMutex     sync.Mutex
+	sessionsMutex sync.Mutex
+	sessions      map[string]chan dataConn
+	floods        map[string]*floodCounter
+	pings         map[string]bool
+	churns        map[string]bool
+
+	tlsOnce sync.Once
+	tls     *tls.Config
+	tlsErr  error
+}
+
+type dataConn struct {
+	conn   net.Conn
+	reader io.Reader
+}
+
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+
+	_ = conn.SetReadDeadline(time.Now().Add(headerTimeout))
+	first, err := reader.Peek(1)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	if first[0] == tlsHandshakeRecord {
+		config, err := s.tlsConfig()
+		if err != nil {
+			conn.Close()
+			return
+		}
+		conn = tls.Server(&bufferedConn{conn, reader}, config)
+		reader = bufio.NewReader(conn)
+	}
+
+	header, err := readHeader(conn, reader)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	switch header.Type {
+	case messageHello:
+		defer conn.Close()
+		s.control(conn, reader, header)
+	case messageData:
+		s.attach(header.Session, dataConn{conn, reader})
+	case messageChurnConnection:
+		s.churn(conn, header.Session)
+	default:
+		conn.Close()
+	}
+}
+
+func (s *Server) control(conn net.Conn, reader *bufio.Reader, hello message) {
+	if hello.Version != ProtocolVersion {
+		_ = writeMessage(conn, message{
+			Type:    messageHello,
+			Version: ProtocolVersion,
+			Error:   fmt.Sprintf("client protocol version %v doesn't match server version %v", hello.Version, ProtocolVersion),
+		})
+		return
+	}
+
+	authenticated := false
+	if hello.Token != "" {
+		if s.MeshToken == "" || subtle.ConstantTimeCompare([]byte(hello.Token), []byte(s.MeshToken)) != 1 {
+			_ = writeMessage(conn, message{Type: messageHello, Version: ProtocolVersion, Error: "mesh is disabled or the token is wrong"})
+			return
+		}
+		authenticated = true
+	}
+
+	peer := s.Peer
+	welcome := message{Type: messageHello, Version: ProtocolVersion, Peer: &peer}
+	if authenticated {
+		welcome.Placement = s.Placement.String()
+	}
+	if err := writeMessage(conn, welcome); err != nil {
+		return
+	}
+
+	// targets the mesh coordinator on this connection registered
+	meshPeers := map[string]bool{}
+
+	decoder := json.NewDecoder(reader)
+	for {
+		var request message
+		if err := decoder.Decode(&request); err != nil {
+			return
+		}
+
+		var reply message
+		switch request.Type {
+		case messageTCP:
+			reply = s.tcp(conn, request)
+		case messageUDPPing:
+			reply = s.udpPing(conn, decoder)
+		case messageUDPFlood:
+			reply = s.udpFlood(conn, decoder)
+		case messageChurn:
+			reply = s.churnTest(conn, decoder)
+		case messageMeshPeers:
+			if !authenticated {
+				reply = message{Type: messageResult, Error: "mesh requires a token"}
+				break
+			}
+			for _, target := range request.Peers {
+				meshPeers[target] = true
+			}
+			reply = message{Type: messageResult}
+		case messageMesh:
+			if !authenticated {
+				reply = message{Type: messageResult, Error: "mesh requires a token"}
+				break
+			}
+			if !meshPeers[request.Target] {
+				reply = message{Type: messageResult, Error: fmt.Sprintf("%v is not a registered mesh peer", request.Target)}
+				break
+			}
+			reply = s.meshPair(request)
+		default:
+			reply = message{Type: messageResult, Error: fmt.Sprintf("unknown request %v", request.Type)}
+		}
+
+		if err := writeMessage(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// sessionLength is the length of session ids in hex
+const sessionLength = 32
+
+func newSessionId() string {
+	id := make([]byte, sessionLength/2)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func (s *Server) newSession(streams int) (string, chan dataConn) {
+	session := newSessionId()
+	conns := make(chan dataConn, streams)
+
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+	if s.sessions == nil {
+		s.sessions = map[string]chan dataConn{}
+	}
+	s.sessions[session] = conns
+
+	return session, conns
+}
+
+func (s *Server) closeSession(session string) {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+
+	// close streams that connected too late
+	conns := s.sessions[session]
+	delete(s.sessions, session)
+	for {
+		select {
+		case data := <-conns:
+			data.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// attach hands a data connection to the test waiting for it.
+func (s *Server) attach(session string, data dataConn) {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+
+	conns, ok := s.sessions[session]
+	if !ok {
+		data.conn.Close()
+		return
+	}
+
+	select {
+	case conns <- data:
+	default:
+		// more streams than asked for
+		data.conn.Close()
+	}
+}
+
+// waitForStreams waits for the client to open all data connections.
+func waitForStreams(conns chan dataConn, streams int) ([]dataConn, error) {
+	var result []dataConn
+	timeout := time.After(headerTimeout)
+	for len(result) < streams {
+		select {
+		case data := <-conns:
+			result = append(result, data)
+		case <-timeout:
+			return result, fmt.Errorf("only %v of %v streams connected", len(result), streams)
+		}
+	}
+	return result, nil
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func (s *Server) tcp(conn net.Conn, request message) message {
+	if request.Streams < 1 || request.Streams > MaxStreams {
+		return message{Type: messageResult, Error: fmt.Sprintf("streams must be between 1 and %v", MaxStreams)}
+	}
+	if request.Duration <= 0 || request.Duration > MaxDuration {
+		return message{Type: messageResult, Error: fmt.Sprintf("duration must be between 0 and %v", MaxDuration)}
+	}
+
+	s.testMutex.Lock()
+	defer s.testMutex.Unlock()
+
+	session, conns := s.newSession(request.Streams)
+	defer s.closeSession(session)
+
+	if err := writeMessage(conn, message{Type: messageReady, Session: session}); err != nil {
+		return message{Type: messageResult, Error: err.Error()}
+	}
+
+	streams, err := waitForStreams(conns, request.Streams)
+	defer func() {
+		for _, stream := range streams {
+			stream.conn.Close()
+		}
+	}()
+	if err != nil {
+		return message{Type: messageResult, Error: err.Error()}
+	}
+
+	dataConns := make([]net.Conn, len(streams))
+	readers := make([]io.Reader, len(streams))
+	for i, stream := range streams {
+		dataConns[i] = stream.conn
+		readers[i] = stream.reader
+	}
+
+	if request.Reverse {
+		// the client measures what it received
+		err = sendAll(dataConns, request.Duration)
+		return message{Type: messageResult, Error: errorString(err)}
+	}
+
+	result, err := receiveAll(dataConns, readers, request.Duration)
+	if err != nil {
+		return message{Type: messageResult, Error: err.Error()}
+	}
+	return message{Type: messageResult, Result: &result}
+}
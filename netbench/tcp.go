@@ -0,0 +1,136 @@
+package netbench
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+const tcpBufferSize = 128 * 1024
+
+type TCPResult struct {
+	// Gbps of every stream
+	Streams []float64 `json:"streams"`
+	// Gbps of all streams together
+	Aggregate float64 `json:"aggregate"`
+}
+
+type streamMeasurement struct {
+	bytes int64
+	start time.Time
+	end   time.Time
+}
+
+func gbps(bytes int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(bytes) * 8 / elapsed.Seconds() / 1e9
+}
+
+// sendStream writes to conn for the given duration and then closes the write side so the receiver sees the end.
+func sendStream(conn net.Conn, duration time.Duration) error {
+	buffer := make([]byte, tcpBufferSize)
+	rand.New(rand.NewSource(1)).Read(buffer)
+
+	deadline := time.Now().Add(duration)
+	// don't block forever if the receiver goes away
+	_ = conn.SetWriteDeadline(deadline.Add(headerTimeout))
+	for time.Now().Before(deadline) {
+		if _, err := conn.Write(buffer); err != nil {
+			return err
+		}
+	}
+
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		return tcp.CloseWrite()
+	}
+	return nil
+}
+
+// receiveStream reads until the sender closes the stream.
+func receiveStream(conn net.Conn, reader io.Reader, duration time.Duration) (streamMeasurement, error) {
+	buffer := make([]byte, tcpBufferSize)
+	measurement := streamMeasurement{}
+
+	_ = conn.SetReadDeadline(time.Now().Add(duration + 2*headerTimeout))
+	for {
+		n, err := reader.Read(buffer)
+		if n > 0 {
+			now := time.Now()
+			if measurement.bytes == 0 {
+				measurement.start = now
+			}
+			measurement.bytes += int64(n)
+			measurement.end = now
+		}
+		if err == io.EOF {
+			return measurement, nil
+		}
+		if err != nil {
+			return measurement, err
+		}
+	}
+}
+
+// sendAll sends on all connections in parallel.
+func sendAll(conns []net.Conn, duration time.Duration) error {
+	errs := make([]error, len(conns))
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		wg.Add(1)
+		go func(i int, conn net.Conn) {
+			defer wg.Done()
+			errs[i] = sendStream(conn, duration)
+		}(i, conn)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receiveAll receives on all connections in parallel. The aggregate counts from the first stream starting to the
+// last stream ending.
+func receiveAll(conns []net.Conn, readers []io.Reader, duration time.Duration) (TCPResult, error) {
+	measurements := make([]streamMeasurement, len(conns))
+	errs := make([]error, len(conns))
+	var wg sync.WaitGroup
+	for i := range conns {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			measurements[i], errs[i] = receiveStream(conns[i], readers[i], duration)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return TCPResult{}, err
+		}
+	}
+
+	result := TCPResult{}
+	var total int64
+	var start, end time.Time
+	for _, measurement := range measurements {
+		result.Streams = append(result.Streams, gbps(measurement.bytes, measurement.end.Sub(measurement.start)))
+		total += measurement.bytes
+		if start.IsZero() || measurement.start.Before(start) {
+			start = measurement.start
+		}
+		if measurement.end.After(end) {
+			end = measurement.end
+		}
+	}
+	result.Aggregate = gbps(total, end.Sub(start))
+
+	return result, nil
+}
@@ -0,0 +1,189 @@
+package netbench
+
+import (
+	"cloud-z/reporting"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer runs a server on a random loopback port for TCP and UDP and returns its address.
+func startServer(t *testing.T, server *Server) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	packetConns, err := ListenUDP(listener.Addr().String(), 4)
+	if err != nil {
+		listener.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		listener.Close()
+		for _, packetConn := range packetConns {
+			packetConn.Close()
+		}
+	})
+
+	go server.Serve(listener)
+	for _, packetConn := range packetConns {
+		go server.ServeUDP(packetConn)
+	}
+
+	return listener.Addr().String()
+}
+
+func dialServer(t *testing.T, address string) *Client {
+	t.Helper()
+
+	client, err := Dial(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return client
+}
+
+func TestHello(t *testing.T) {
+	server := &Server{
+		Peer: reporting.PeerReport{
+			Cloud:            "AWS",
+			InstanceType:     "c6i.large",
+			Region:           "us-east-1",
+			AvailabilityZone: "us-east-1a",
+		},
+	}
+	client := dialServer(t, startServer(t, server))
+
+	if client.Peer != server.Peer {
+		t.Errorf("peer is %+v, expected %+v", client.Peer, server.Peer)
+	}
+}
+
+func TestHelloVersionMismatch(t *testing.T) {
+	address := startServer(t, &Server{})
+
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := writeMessage(conn, message{Type: messageHello, Version: ProtocolVersion + 1}); err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{conn: conn, decoder: json.NewDecoder(conn)}
+	if _, err := client.read(); err == nil || !strings.Contains(err.Error(), "doesn't match") {
+		t.Errorf("expected version mismatch error, got %v", err)
+	}
+}
+
+func TestUnknownRequest(t *testing.T) {
+	client := dialServer(t, startServer(t, &Server{}))
+
+	if _, err := client.request(message{Type: "nonsense"}); err == nil {
+		t.Error("expected an error for an unknown request")
+	}
+
+	// the control channel must still work afterwards
+	if _, err := client.TCP(1, 100*time.Millisecond, false); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRequestLimits(t *testing.T) {
+	client := dialServer(t, startServer(t, &Server{}))
+
+	if _, err := client.TCP(MaxStreams+1, time.Second, false); err == nil {
+		t.Error("expected an error for too many streams")
+	}
+	if _, err := client.TCP(1, MaxDuration+time.Second, false); err == nil {
+		t.Error("expected an error for a long duration")
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	// a server that accepts and never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	client := &Client{conn: conn, decoder: json.NewDecoder(conn)}
+	start := time.Now()
+	_, err = client.readWithin(100 * time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("read took %v", elapsed)
+	}
+}
+
+func TestTCP(t *testing.T) {
+	client := dialServer(t, startServer(t, &Server{}))
+
+	for _, reverse := range []bool{false, true} {
+		for _, streams := range []int{1, 4} {
+			result, err := client.TCP(streams, 200*time.Millisecond, reverse)
+			if err != nil {
+				t.Fatalf("reverse %v streams %v: %v", reverse, streams, err)
+			}
+			if len(result.Streams) != streams {
+				t.Errorf("reverse %v streams %v: got %v stream results", reverse, streams, len(result.Streams))
+			}
+			for i, stream := range result.Streams {
+				if stream <= 0 {
+					t.Errorf("reverse %v streams %v: stream %v measured %v Gbps", reverse, streams, i, stream)
+				}
+			}
+			if result.Aggregate <= 0 {
+				t.Errorf("reverse %v streams %v: aggregate is %v Gbps", reverse, streams, result.Aggregate)
+			}
+		}
+	}
+}
+
+func TestBenchmark(t *testing.T) {
+	server := &Server{Peer: reporting.PeerReport{InstanceType: "c6i.large", AvailabilityZone: "us-east-1a"}}
+	address := startServer(t, server)
+
+	report := &reporting.Report{}
+	Benchmark(report, Options{
+		Peer:     address,
+		Streams:  []int{2},
+		Duration: 100 * time.Millisecond,
+		Send:     true,
+		Receive:  true,
+	})
+
+	if len(report.Errors) > 0 {
+		t.Fatal(report.Errors)
+	}
+	for _, name := range []string{"net-tcp-send-p2", "net-tcp-receive-p2"} {
+		benchmark, ok := report.Benchmarks[name]
+		if !ok {
+			t.Errorf("%v is missing", name)
+			continue
+		}
+		if benchmark.Peer == nil || *benchmark.Peer != server.Peer {
+			t.Errorf("%v peer is %v, expected %+v", name, benchmark.Peer, server.Peer)
+		}
+		if len(benchmark.Streams) != 2 || benchmark.Result <= 0 {
+			t.Errorf("%v has %v streams and %v Gbps", name, len(benchmark.Streams), benchmark.Result)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package netbench
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+/*
+   UDP benchmarks use the same port number as the TCP control channel.
+
+   Ping sends one small packet at a time and waits for the server to echo it
+   back. Pings carry the session id the server handed out on the control
+   channel and are only echoed while that test runs, so the server can't be
+   used to reflect spoofed traffic. Latency is the round trip and jitter is the RFC 3550 interarrival
+   jitter estimate, a running average of the difference between consecutive
+   round trips that moves 1/16 of the way to every new difference.
+
+   Flood blasts small packets from parallel sockets as fast as possible. The
+   server counts what actually arrived so the result is the packet rate the
+   path and the receiving instance can handle, and the loss rate shows how
+   much was dropped on the way. The server reads with one socket per CPU
+   sharing the port so its own receive loop isn't what gets measured.
+*/
+
+const pingTimeout = time.Second
+const floodPacketSize = 64
+const udpReadBuffer = 4 * 1024 * 1024
+
+var packetMagic = []byte("CZN1")
+
+const (
+	packetPing  = 'p'
+	packetFlood = 'f'
+)
+
+type UDPPingResult struct {
+	Latencies []time.Duration
+	// RFC 3550 jitter estimate over consecutive round trips
+	Jitter time.Duration
+	Sent   int
+	Lost   int
+}
+
+type UDPFloodResult struct {
+	Sent     int64   `json:"sent"`
+	Received int64   `json:"received"`
+	Seconds  float64 `json:"seconds"`
+}
+
+func (r UDPFloodResult) PacketsPerSecond() float64 {
+	if r.Seconds <= 0 {
+		return 0
+	}
+	return float64(r.Received) / r.Seconds
+}
+
+func (r UDPFloodResult) Loss() float64 {
+	if r.Sent == 0 {
+		return 0
+	}
+	return 100 * (1 - float64(r.Received)/float64(r.Sent))
+}
+
+// floodCounter is updated atomically by every UDP reader.
+type floodCounter struct {
+	packets int64
+	// first and last packet in Unix nanoseconds
+	first int64
+	last  int64
+}
+
+func (counter *floodCounter) add() {
+	now := time.Now().UnixNano()
+	atomic.CompareAndSwapInt64(&counter.first, 0, now)
+	atomic.AddInt64(&counter.packets, 1)
+	atomic.StoreInt64(&counter.last, now)
+}
+
+func pingPacket(session string, seq uint64) []byte {
+	packet := make([]byte, len(packetMagic)+1+sessionLength+8)
+	copy(packet, packetMagic)
+	packet[len(packetMagic)] = packetPing
+	copy(packet[len(packetMagic)+1:], session)
+	binary.BigEndian.PutUint64(packet[len(packetMagic)+1+sessionLength:], seq)
+	return packet
+}
+
+func floodPacket(session string) []byte {
+	packet := make([]byte, floodPacketSize)
+	copy(packet, packetMagic)
+	packet[len(packetMagic)] = packetFlood
+	copy(packet[len(packetMagic)+1:], session)
+	return packet
+}
+
+// ServeUDP echoes pings and counts flood packets for the tests started on the control channel, packets of unknown
+// sessions are dropped. Call it for every
+// socket ListenUDP returned.
+func (s *Server) ServeUDP(conn net.PacketConn) error {
+	if udp, ok := conn.(*net.UDPConn); ok {
+		// so a slow reader doesn't count as loss on the network
+		_ = udp.SetReadBuffer(udpReadBuffer)
+	}
+
+	// most packets belong to the same flood so the lookup is only done when the session changes
+	var session []byte
+	var counter *floodCounter
+
+	buffer := make([]byte, 2048)
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return err
+		}
+		packet := buffer[:n]
+		if n <= len(packetMagic) || !bytes.Equal(packet[:len(packetMagic)], packetMagic) {
+			continue
+		}
+
+		switch packet[len(packetMagic)] {
+		case packetPing:
+			// looked up every time so nothing is echoed once the test is over
+			payload := packet[len(packetMagic)+1:]
+			if len(payload) >= sessionLength && s.pingSession(payload[:sessionLength]) {
+				_, _ = conn.WriteTo(packet, addr)
+			}
+		case packetFlood:
+			payload := packet[len(packetMagic)+1:]
+			// session ids are hex strings of fixed length
+			if len(payload) < sessionLength {
+				continue
+			}
+			if counter == nil || !bytes.Equal(payload[:sessionLength], session) {
+				counter = s.floodCounter(payload[:sessionLength])
+				if counter == nil {
+					continue
+				}
+				session = append(session[:0], payload[:sessionLength]...)
+			}
+			counter.add()
+		}
+	}
+}
+
+// floodCounter returns the counter of a flood test or nil if the session is unknown.
+func (s *Server) floodCounter(session []byte) *floodCounter {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+	return s.floods[string(session)]
+}
+
+// pingSession tells if pings of a session are echoed.
+func (s *Server) pingSession(session []byte) bool {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+	return s.pings[string(session)]
+}
+
+// waitForDone waits for the client to say it finished sending.
+func waitForDone(conn net.Conn, decoder *json.Decoder) error {
+	_ = conn.SetReadDeadline(time.Now().Add(MaxDuration + headerTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var done message
+	if err := decoder.Decode(&done); err != nil {
+		return err
+	}
+	if done.Type != messageDone {
+		return fmt.Errorf("unexpected %v message", done.Type)
+	}
+	return nil
+}
+
+func (s *Server) udpPing(conn net.Conn, decoder *json.Decoder) message {
+	s.testMutex.Lock()
+	defer s.testMutex.Unlock()
+
+	session := newSessionId()
+	s.sessionsMutex.Lock()
+	if s.pings == nil {
+		s.pings = map[string]bool{}
+	}
+	s.pings[session] = true
+	s.sessionsMutex.Unlock()
+
+	defer func() {
+		s.sessionsMutex.Lock()
+		delete(s.pings, session)
+		s.sessionsMutex.Unlock()
+	}()
+
+	if err := writeMessage(conn, message{Type: messageReady, Session: session}); err != nil {
+		return message{Type: messageResult, Error: err.Error()}
+	}
+	return message{Type: messageResult, Error: errorString(waitForDone(conn, decoder))}
+}
+
+func (s *Server) udpFlood(conn net.Conn, decoder *json.Decoder) message {
+	s.testMutex.Lock()
+	defer s.testMutex.Unlock()
+
+	session := newSessionId()
+	counter := &floodCounter{}
+	s.sessionsMutex.Lock()
+	if s.floods == nil {
+		s.floods = map[string]*floodCounter{}
+	}
+	s.floods[session] = counter
+	s.sessionsMutex.Unlock()
+
+	defer func() {
+		s.sessionsMutex.Lock()
+		delete(s.floods, session)
+		s.sessionsMutex.Unlock()
+	}()
+
+	if err := writeMessage(conn, message{Type: messageReady, Session: session}); err != nil {
+		return message{Type: messageResult, Error: err.Error()}
+	}
+	if err := waitForDone(conn, decoder); err != nil {
+		return message{Type: messageResult, Error: err.Error()}
+	}
+
+	// let packets still in flight arrive
+	time.Sleep(100 * time.Millisecond)
+
+	first := atomic.LoadInt64(&counter.first)
+	last := atomic.LoadInt64(&counter.last)
+	return message{Type: messageResult, Flood: &UDPFloodResult{
+		Received: atomic.LoadInt64(&counter.packets),
+		Seconds:  time.Duration(last - first).Seconds(),
+	}}
+}
+
+// UDPPing sends pings one after the other for the given duration.
+func (c *Client) UDPPing(duration time.Duration) (UDPPingResult, error) {
+	result := UDPPingResult{}
+
+	ready, err := c.request(message{Type: messageUDPPing})
+	if err != nil {
+		return result, err
+	}
+	if ready.Type != messageReady {
+		return result, fmt.Errorf("unexpected %v message", ready.Type)
+	}
+
+	err = c.ping(ready.Session, duration, &result)
+
+	_, doneErr := c.request(message{Type: messageDone})
+	if err != nil {
+		return result, err
+	}
+	if doneErr != nil {
+		return result, doneErr
+	}
+	if len(result.Latencies) == 0 {
+		return result, fmt.Errorf("all %v pings were lost", result.Sent)
+	}
+
+	return result, nil
+}
+
+func (c *Client) ping(session string, duration time.Duration, result *UDPPingResult) error {
+	conn, err := net.Dial("udp", c.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	buffer := make([]byte, 64)
+	var jitter float64
+	deadline := time.Now().Add(duration)
+	for seq := uint64(0); time.Now().Before(deadline); seq++ {
+		packet := pingPacket(session, seq)
+		start := time.Now()
+		if _, err := conn.Write(packet); err != nil {
+			return err
+		}
+		result.Sent++
+
+		_ = conn.SetReadDeadline(start.Add(pingTimeout))
+		for {
+			n, err := conn.Read(buffer)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				result.Lost++
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(buffer[:n], packet) {
+				// late echo of an earlier ping we already counted as lost
+				continue
+			}
+
+			latency := time.Since(start)
+			if len(result.Latencies) > 0 {
+				diff := latency - result.Latencies[len(result.Latencies)-1]
+				if diff < 0 {
+					diff = -diff
+				}
+				jitter += (float64(diff) - jitter) / 16
+			}
+			result.Latencies = append(result.Latencies, latency)
+			break
+		}
+	}
+
+	result.Jitter = time.Duration(jitter)
+
+	return nil
+}
+
+// UDPFlood sends small packets from parallel sockets as fast as possible for the given duration.
+func (c *Client) UDPFlood(streams int, duration time.Duration) (UDPFloodResult, error) {
+	ready, err := c.request(message{Type: messageUDPFlood})
+	if err != nil {
+		return UDPFloodResult{}, err
+	}
+	if ready.Type != messageReady {
+		return UDPFloodResult{}, fmt.Errorf("unexpected %v message", ready.Type)
+	}
+
+	sent, err := c.flood(ready.Session, streams, duration)
+
+	reply, doneErr := c.request(message{Type: messageDone})
+	if err != nil {
+		return UDPFloodResult{}, err
+	}
+	if doneErr != nil {
+		return UDPFloodResult{}, doneErr
+	}
+	if reply.Flood == nil {
+		return UDPFloodResult{}, fmt.Errorf("server sent no result")
+	}
+
+	result := *reply.Flood
+	result.Sent = sent
+	return result, nil
+}
+
+func (c *Client) flood(session string, streams int, duration time.Duration) (int64, error) {
+	sent := make([]int64, streams)
+	errs := make(chan error, streams)
+	deadline := time.Now().Add(duration)
+
+	for i := 0; i < streams; i++ {
+		go func(i int) {
+			// every socket has its own source port so the flows spread over queues
+			conn, err := net.Dial("udp", c.address)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer conn.Close()
+
+			packet := floodPacket(session)
+			for time.Now().Before(deadline) {
+				if _, err := conn.Write(packet); err != nil {
+					errs <- err
+					return
+				}
+				sent[i]++
+			}
+			errs <- nil
+		}(i)
+	}
+
+	var firstErr error
+	for i := 0; i < streams; i++ {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	var total int64
+	for _, count := range sent {
+		total += count
+	}
+	return total, firstErr
+}
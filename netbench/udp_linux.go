@@ -0,0 +1,40 @@
+package netbench
+
+import (
+	"context"
+	"golang.org/x/sys/unix"
+	"net"
+	"syscall"
+)
+
+// ListenUDP opens sockets UDP sockets on the same address with SO_REUSEPORT so the kernel spreads flows over them and
+// each can have its own reader.
+func ListenUDP(address string, sockets int) ([]net.PacketConn, error) {
+	config := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var sockErr error
+			err := c.Control(func(fd uintptr) {
+				sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+			})
+			if err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+
+	var conns []net.PacketConn
+	for i := 0; i < sockets; i++ {
+		conn, err := config.ListenPacket(context.Background(), "udp", address)
+		if err != nil {
+			for _, conn := range conns {
+				conn.Close()
+			}
+			return nil, err
+		}
+		conns = append(conns, conn)
+		// the rest must bind to the same port even if the address asked for any port
+		address = conn.LocalAddr().String()
+	}
+	return conns, nil
+}
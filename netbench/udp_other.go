@@ -0,0 +1,14 @@
+//go:build !linux
+
+package netbench
+
+import "net"
+
+// ListenUDP opens one UDP socket as only Linux spreads flows over sockets sharing a port.
+func ListenUDP(address string, sockets int) ([]net.PacketConn, error) {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	return []net.PacketConn{conn}, nil
+}
@@ -0,0 +1,69 @@
+package netbench
+
+import (
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestUDPPing(t *testing.T) {
+	client := dialServer(t, startServer(t, &Server{}))
+
+	result, err := client.UDPPing(200 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sent == 0 || len(result.Latencies)+result.Lost != result.Sent {
+		t.Errorf("sent %v, got %v back and lost %v", result.Sent, len(result.Latencies), result.Lost)
+	}
+	for _, latency := range result.Latencies {
+		if latency <= 0 || latency >= pingTimeout {
+			t.Errorf("round trip took %v", latency)
+			break
+		}
+	}
+	if result.Jitter < 0 || result.Jitter >= pingTimeout {
+		t.Errorf("jitter is %v", result.Jitter)
+	}
+}
+
+func TestUDPPingUnknownSession(t *testing.T) {
+	address := startServer(t, &Server{})
+
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// no test is running so neither a made up session nor a short packet may be echoed
+	for _, packet := range [][]byte{pingPacket(newSessionId(), 1), append(append([]byte{}, packetMagic...), packetPing)} {
+		if _, err := conn.Write(packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, err := conn.Read(make([]byte, 64)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected no echo, got %v", err)
+	}
+}
+
+func TestUDPFlood(t *testing.T) {
+	client := dialServer(t, startServer(t, &Server{}))
+
+	result, err := client.UDPFlood(4, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Received <= 0 || result.Received > result.Sent {
+		t.Errorf("sent %v packets and server received %v", result.Sent, result.Received)
+	}
+	if result.PacketsPerSecond() <= 0 {
+		t.Errorf("%v packets per second", result.PacketsPerSecond())
+	}
+	if loss := result.Loss(); loss < 0 || loss >= 100 {
+		t.Errorf("loss is %v%%", loss)
+	}
+}
@@ -3,6 +3,7 @@ package providers
 import (
 	"cloud-z/metadata"
 	"cloud-z/reporting"
+	"context"
 	"errors"
 	"fmt"
 )
@@ -22,45 +23,55 @@ func (provider *AwsProvider) Detect() bool {
 	return server == "EC2ws"
 }
 
-func (provider *AwsProvider) getMetadataJsonWithPossibleToken(url string, target interface{}) error {
+// tokenHeader returns the IMDSv2 token header if a token was needed before.
+func (provider *AwsProvider) tokenHeader() (string, string) {
 	if provider.token != nil {
-		return metadata.GetMetadataJson(url, target, "X-aws-ec2-metadata-token", *provider.token)
+		return "X-aws-ec2-metadata-token", *provider.token
 	}
+	return "", ""
+}
 
-	err := metadata.GetMetadataJson(url, target, "", "")
-	if errors.Is(err, metadata.UnauthorizedError) {
-		tokenValue, err := metadata.PutMetadata("/latest/api/token", "X-aws-ec2-metadata-token-ttl-seconds", "120")
-		if err != nil {
-			return err
-		}
-		provider.token = &tokenValue
-	} else {
+// refreshToken gets a new IMDSv2 token. Tokens expire after 120 seconds, so requests made later like the metadata
+// latency benchmark get a 401 and need a new one.
+func (provider *AwsProvider) refreshToken(ctx context.Context) error {
+	tokenValue, err := metadata.PutMetadataContext(ctx, "/latest/api/token", "X-aws-ec2-metadata-token-ttl-seconds", "120")
+	if err != nil {
+		return err
+	}
+	provider.token = &tokenValue
+	return nil
+}
+
+func (provider *AwsProvider) getMetadataJsonWithPossibleToken(url string, target interface{}) error {
+	headerName, headerValue := provider.tokenHeader()
+	err := metadata.GetMetadataJson(url, target, headerName, headerValue)
+	if !errors.Is(err, metadata.UnauthorizedError) {
 		return err
 	}
 
-	return metadata.GetMetadataJson(url, target, "X-aws-ec2-metadata-token", *provider.token)
+	if err := provider.refreshToken(context.Background()); err != nil {
+		return err
+	}
+	headerName, headerValue = provider.tokenHeader()
+	return metadata.GetMetadataJson(url, target, headerName, headerValue)
 }
 
 func (provider *AwsProvider) getMetadataTextWithPossibleToken(url string) (string, error) {
-	// TODO refactor to share code with JSON function
-	if provider.token != nil {
-		return metadata.GetMetadataText(url, "X-aws-ec2-metadata-token", *provider.token)
-	}
+	return provider.getMetadataTextContext(context.Background(), url)
+}
 
-	result, err := metadata.GetMetadataText(url, "", "")
-	if errors.Is(err, metadata.UnauthorizedError) {
-		tokenValue, err := metadata.PutMetadata("/latest/api/token", "X-aws-ec2-metadata-token-ttl-seconds", "120")
-		if err != nil {
-			return "", err
-		}
-		provider.token = &tokenValue
+func (provider *AwsProvider) getMetadataTextContext(ctx context.Context, url string) (string, error) {
+	headerName, headerValue := provider.tokenHeader()
+	result, err := metadata.GetMetadataTextContext(ctx, url, headerName, headerValue)
+	if !errors.Is(err, metadata.UnauthorizedError) {
+		return result, err
+	}
 
-		return metadata.GetMetadataText(url, "X-aws-ec2-metadata-token", *provider.token)
-	} else if err != nil {
+	if err := provider.refreshToken(ctx); err != nil {
 		return "", err
-	} else {
-		return result, nil
 	}
+	headerName, headerValue = provider.tokenHeader()
+	return metadata.GetMetadataTextContext(ctx, url, headerName, headerValue)
 }
 
 type instanceIdentityDocumentType struct {
@@ -113,4 +124,13 @@ func (provider *AwsProvider) GetData(report *reporting.Report) {
 	if err != nil {
 		report.AddError(fmt.Sprintf("Unable to get az: %v", err))
 	}
+
+	// only available for instances in a placement group
+	report.Placement.Group, _ = provider.getMetadataTextWithPossibleToken("/2021-07-15/meta-data/placement/group-name")
+	report.Placement.Partition, _ = provider.getMetadataTextWithPossibleToken("/2021-07-15/meta-data/placement/partition-number")
+}
+
+func (provider *AwsProvider) ProbeMetadata(ctx context.Context) error {
+	_, err := provider.getMetadataTextContext(ctx, "/2021-07-15/meta-data/instance-type")
+	return err
 }
@@ -3,6 +3,7 @@ package providers
 import (
 	"cloud-z/metadata"
 	"cloud-z/reporting"
+	"context"
 	"fmt"
 	"strings"
 )
@@ -21,7 +22,11 @@ func (provider *AzureProvider) Detect() bool {
 }
 
 func (provider *AzureProvider) getMetadata(url string) (string, error) {
-	return metadata.GetMetadataText(url, "Metadata", "true")
+	return provider.getMetadataContext(context.Background(), url)
+}
+
+func (provider *AzureProvider) getMetadataContext(ctx context.Context, url string) (string, error) {
+	return metadata.GetMetadataTextContext(ctx, url, "Metadata", "true")
 }
 
 func (provider *AzureProvider) GetData(report *reporting.Report) {
@@ -45,4 +50,13 @@ func (provider *AzureProvider) GetData(report *reporting.Report) {
 		}
 		*target = data
 	}
+
+	// placement group is only available for scale sets
+	report.Placement.Group, _ = provider.getMetadata("/metadata/instance/compute/placementGroupId?api-version=2021-02-01&format=text")
+	report.Placement.Partition, _ = provider.getMetadata("/metadata/instance/compute/platformFaultDomain?api-version=2017-08-01&format=text")
+}
+
+func (provider *AzureProvider) ProbeMetadata(ctx context.Context) error {
+	_, err := provider.getMetadataContext(ctx, "/metadata/instance/compute/vmSize?api-version=2017-08-01&format=text")
+	return err
 }
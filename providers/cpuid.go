@@ -1,8 +1,12 @@
 package providers
 
 import (
+	"bufio"
 	"cloud-z/reporting"
 	"github.com/klauspost/cpuid/v2"
+	"os"
+	"strconv"
+	"strings"
 )
 
 func GetCPUInfo(report *reporting.Report) {
@@ -21,4 +25,38 @@ func GetCPUInfo(report *reporting.Report) {
 	report.CPU.CacheL3 = cpuid.CPU.Cache.L3
 	report.CPU.CacheLine = cpuid.CPU.CacheLine
 	report.CPU.Features = cpuid.CPU.FeatureSet()
+
+	// cpuid doesn't know the frequency of ARM CPUs and of CPUs behind some hypervisors, but Linux often does
+	if report.CPU.MHz == 0 {
+		report.CPU.MHz = procCPUInfoMHz()
+	}
+	if report.CPU.BoostFrequency == 0 {
+		if kHz, err := readSysInt("/sys/devices/system/cpu/cpu0/cpufreq/cpuinfo_max_freq"); err == nil {
+			report.CPU.BoostFrequency = int(kHz * 1000)
+		}
+	}
+}
+
+// procCPUInfoMHz returns the "cpu MHz" of the first CPU in /proc/cpuinfo, or 0 if it's not there.
+func procCPUInfoMHz() int {
+	file, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found || strings.TrimSpace(key) != "cpu MHz" {
+			continue
+		}
+		mhz, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return 0
+		}
+		return int(mhz)
+	}
+
+	return 0
 }
@@ -0,0 +1,166 @@
+package providers
+
+import (
+	"bufio"
+	"cloud-z/reporting"
+	"errors"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pseudo filesystems with nothing to store files on
+var ignoredFilesystems = map[string]bool{
+	"devtmpfs": true,
+	"devpts":   true,
+	"mqueue":   true,
+	"efivarfs": true,
+}
+
+var inMemoryFilesystems = map[string]bool{
+	"tmpfs": true,
+	"ramfs": true,
+}
+
+// statfsTimeout is how long to wait for a filesystem to report its size. Network filesystems whose server went away
+// can hang forever.
+const statfsTimeout = 2 * time.Second
+
+var statfsTimeoutError = errors.New("timed out")
+
+// mountBlockDevice maps a mount's device number to the disk it is on, skipping partitions.
+func mountBlockDevice(majorMinor string) string {
+	path, err := filepath.EvalSymlinks(filepath.Join("/sys/dev/block", majorMinor))
+	if err != nil {
+		return ""
+	}
+
+	if _, err := os.Stat(filepath.Join(path, "partition")); err == nil {
+		return filepath.Base(filepath.Dir(path))
+	}
+
+	return filepath.Base(path)
+}
+
+// mountSource keeps local device names and drops network sources that have host names or addresses in them.
+func mountSource(source string) string {
+	if strings.HasPrefix(source, "/dev/") || !strings.ContainsAny(source, ":/@") {
+		return source
+	}
+	return ""
+}
+
+func mountOptions(mountOptions string, superOptions string) []string {
+	var options []string
+	seen := map[string]bool{}
+	for _, option := range strings.Split(mountOptions+","+superOptions, ",") {
+		// network filesystems put server and client addresses in options
+		if option == "" || seen[option] || strings.Contains(option, "addr=") {
+			continue
+		}
+		seen[option] = true
+		options = append(options, option)
+	}
+	return options
+}
+
+func readMountInfo() ([]reporting.FilesystemReport, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var filesystems []reporting.FilesystemReport
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 28 1 254:0 / / rw,relatime shared:1 - ext4 /dev/vda rw,discard
+		fields := strings.Fields(scanner.Text())
+		separator := -1
+		for i, field := range fields {
+			if field == "-" {
+				separator = i
+				break
+			}
+		}
+		if separator < 6 || len(fields) < separator+4 {
+			continue
+		}
+
+		fsType := fields[separator+1]
+		if ignoredFilesystems[fsType] {
+			continue
+		}
+
+		mountPoint := unescapeMountInfo(fields[4])
+		filesystems = append(filesystems, reporting.FilesystemReport{
+			Path:        mountPoint,
+			MountPoint:  redactMountPoint(mountPoint),
+			Source:      mountSource(unescapeMountInfo(fields[separator+2])),
+			BlockDevice: mountBlockDevice(fields[2]),
+			Type:        fsType,
+			Options:     mountOptions(fields[5], fields[separator+3]),
+			Overlay:     fsType == "overlay" || fsType == "aufs",
+			InMemory:    inMemoryFilesystems[fsType],
+		})
+	}
+
+	return filesystems, scanner.Err()
+}
+
+// statfs runs unix.Statfs on another goroutine and gives up after statfsTimeout. The goroutine of a filesystem that
+// doesn't respond is left behind, as nothing can interrupt it.
+func statfs(path string) (unix.Statfs_t, error) {
+	type result struct {
+		stat unix.Statfs_t
+		err  error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		var stat unix.Statfs_t
+		err := unix.Statfs(path, &stat)
+		done <- result{stat, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.stat, r.err
+	case <-time.After(statfsTimeout):
+		return unix.Statfs_t{}, statfsTimeoutError
+	}
+}
+
+func GetFilesystemInfo(report *reporting.Report) {
+	filesystems, err := readMountInfo()
+	if err != nil {
+		report.AddError(fmt.Sprintf("Unable to read mounts: %v", err))
+		return
+	}
+
+	for _, filesystem := range filesystems {
+		stat, err := statfs(filesystem.Path)
+		if errors.Is(err, statfsTimeoutError) {
+			report.AddError(fmt.Sprintf("Skipped %v filesystem that didn't respond in %v", filesystem.Type, statfsTimeout))
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if stat.Blocks == 0 {
+			// proc, sysfs, cgroup and friends
+			continue
+		}
+
+		filesystem.Size = stat.Blocks * uint64(stat.Bsize)
+		filesystem.Free = stat.Bfree * uint64(stat.Bsize)
+		filesystem.Available = stat.Bavail * uint64(stat.Bsize)
+		filesystem.Inodes = stat.Files
+		filesystem.InodesFree = stat.Ffree
+
+		report.Storage.Filesystems = append(report.Storage.Filesystems, filesystem)
+	}
+}
@@ -0,0 +1,17 @@
+package providers
+
+import "testing"
+
+func TestStatfs(t *testing.T) {
+	stat, err := statfs("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Bsize <= 0 {
+		t.Errorf("block size %v", stat.Bsize)
+	}
+
+	if _, err := statfs("/does/not/exist"); err == nil || err == statfsTimeoutError {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
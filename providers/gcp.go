@@ -3,6 +3,7 @@ package providers
 import (
 	"cloud-z/metadata"
 	"cloud-z/reporting"
+	"context"
 	"fmt"
 	"strings"
 )
@@ -21,7 +22,11 @@ func (provider *GcpProvider) Detect() bool {
 }
 
 func (provider *GcpProvider) getMetadata(url string) (string, error) {
-	return metadata.GetMetadataText(url, "Metadata-Flavor", "Google")
+	return provider.getMetadataContext(context.Background(), url)
+}
+
+func (provider *GcpProvider) getMetadataContext(ctx context.Context, url string) (string, error) {
+	return metadata.GetMetadataTextContext(ctx, url, "Metadata-Flavor", "Google")
 }
 
 func lastPartOfString(s string) string {
@@ -55,4 +60,12 @@ func (provider *GcpProvider) GetData(report *reporting.Report) {
 	// remove project id which is PII
 	report.InstanceType = lastPartOfString(report.InstanceType)
 	report.AvailabilityZone = lastPartOfString(report.AvailabilityZone)
+
+	// only available for instances with a compact placement policy
+	report.Placement.Host, _ = provider.getMetadata("/computeMetadata/v1/instance/attributes/physical_host")
+}
+
+func (provider *GcpProvider) ProbeMetadata(ctx context.Context) error {
+	_, err := provider.getMetadataContext(ctx, "/computeMetadata/v1/instance/machine-type")
+	return err
 }
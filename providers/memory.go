@@ -8,6 +8,7 @@ import (
 	"github.com/cloudfoundry/gosigar"
 	"github.com/digitalocean/go-smbios/smbios"
 	"io"
+	"os"
 )
 
 // https://www.dmtf.org/sites/default/files/standards/documents/DSP0134_3.1.1.pdf
@@ -57,7 +58,7 @@ func (mem *MemoryDevice) formFactor() string {
 		"FB-DIMM",
 	}
 
-	if int(mem.FormFactor) > len(factors) {
+	if int(mem.FormFactor) >= len(factors) {
 		return "<BAD VALUE>"
 	}
 
@@ -99,7 +100,7 @@ func (mem *MemoryDevice) memoryType() string {
 		"LPDDR4",
 	}
 
-	if int(mem.MemoryType) > len(types) {
+	if int(mem.MemoryType) >= len(types) {
 		return "<BAD VALUE>"
 	}
 
@@ -118,14 +119,56 @@ func (mem *MemoryDevice) size() string {
 }
 
 func readString(reader io.Reader, strings []string) string {
+	// string ids are 1-based and 0 means there is no string
 	var stringId uint8
 	binary.Read(reader, binary.LittleEndian, &stringId)
-	if int(stringId) < len(strings) {
-		return strings[stringId]
+	if stringId > 0 && int(stringId) <= len(strings) {
+		return strings[stringId-1]
 	}
 	return ""
 }
 
+// openSMBIOS opens the live SMBIOS table, or a table dump when smbiosFile is set. Both the raw table from
+// /sys/firmware/dmi/tables/DMI and dmidecode --dump-bin files, which start with the entry point, are supported.
+func openSMBIOS(smbiosFile string) (io.ReadCloser, error) {
+	if smbiosFile == "" {
+		// Find SMBIOS data in operating system-specific location.
+		rc, _, err := smbios.Stream()
+		if err != nil {
+			return nil, fmt.Errorf("try sudo: %v", err)
+		}
+		return rc, nil
+	}
+
+	data, err := os.ReadFile(smbiosFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(data, []byte("_SM")) {
+		// dmidecode writes the entry point first and points its table address right after it
+		entryPoint := data
+		if len(entryPoint) > 32 {
+			entryPoint = entryPoint[:32]
+		}
+		ep, err := smbios.ParseEntryPoint(bytes.NewReader(entryPoint))
+		if err != nil {
+			return nil, err
+		}
+		address, size := ep.Table()
+		if address < len(entryPoint) || address >= len(data) {
+			return nil, fmt.Errorf("table address 0x%x is outside of %v", address, smbiosFile)
+		}
+		end := address + size
+		if end > len(data) {
+			end = len(data)
+		}
+		data = data[address:end]
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
 func GetMemoryInfo(report *reporting.Report) {
 	mem := sigar.Mem{}
 	if err := mem.Get(); err != nil {
@@ -134,10 +177,15 @@ func GetMemoryInfo(report *reporting.Report) {
 
 	report.Memory.Total = mem.Total
 
-	// Find SMBIOS data in operating system-specific location.
-	rc, _, err := smbios.Stream()
+	GetSMBIOSInfo(report, "")
+}
+
+// GetSMBIOSInfo decodes memory sticks and platform information from SMBIOS of this machine, or from a table dump when
+// smbiosFile is set.
+func GetSMBIOSInfo(report *reporting.Report, smbiosFile string) {
+	rc, err := openSMBIOS(smbiosFile)
 	if err != nil {
-		report.AddError(fmt.Sprintf("Failed to open SMBIOS stream, try sudo: %v\n", err))
+		report.AddError(fmt.Sprintf("Failed to open SMBIOS stream: %v", err))
 		return
 	}
 	// Be sure to close the stream!
@@ -164,6 +212,8 @@ func GetMemoryInfo(report *reporting.Report) {
 			})
 		}
 	}
+
+	getPlatformInfo(report, records)
 }
 
 func readMemoryDevice(record *smbios.Structure) MemoryDevice {
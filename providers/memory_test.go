@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"bytes"
+	"cloud-z/reporting"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// smbiosGolden is the part of the report that comes from SMBIOS, encoded the way it's submitted.
+type smbiosGolden struct {
+	Sticks   []reporting.MemoryStickReport `json:"sticks"`
+	Platform reporting.PlatformReport      `json:"platform"`
+	Errors   []string                      `json:"errors,omitempty"`
+}
+
+func TestSMBIOSGolden(t *testing.T) {
+	dumps, err := filepath.Glob(filepath.Join("testdata", "smbios", "*.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dumps) == 0 {
+		t.Fatal("no SMBIOS dumps in testdata/smbios")
+	}
+
+	for _, dump := range dumps {
+		t.Run(filepath.Base(dump), func(t *testing.T) {
+			report := &reporting.Report{}
+			GetSMBIOSInfo(report, dump)
+
+			got, err := json.MarshalIndent(smbiosGolden{report.Memory.Sticks, report.Platform, report.Errors}, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := strings.TrimSuffix(dump, ".bin") + ".json"
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test ./providers -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("report doesn't match %v (run go test ./providers -update if the change is intended)\ngot:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestSMBIOSNoPII(t *testing.T) {
+	// serial numbers and asset tags the dumps have that must never be reported, real dumps have them replaced by the
+	// placeholder of scrub.go
+	pii := []string{"SCRUBBED", "SERIAL-0123456789", "ASSET-0123456789", "12345678", "87654321"}
+
+	dumps, err := filepath.Glob(filepath.Join("testdata", "smbios", "*.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dump := range dumps {
+		report := &reporting.Report{}
+		GetSMBIOSInfo(report, dump)
+		encoded, err := json.Marshal(report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, value := range pii {
+			if bytes.Contains(encoded, []byte(value)) {
+				t.Errorf("%v: report contains %v", dump, value)
+			}
+		}
+	}
+}
+
+func TestSMBIOSFileErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name  string
+		data  []byte
+		error string
+	}{
+		{"missing", nil, "Failed to open SMBIOS stream"},
+		{"truncated", []byte{17, 40, 0, 0, 1, 2}, "Failed to decode SMBIOS structures"},
+		{"bad-entry-point", append([]byte("_SM_"), make([]byte, 40)...), "Failed to open SMBIOS stream"},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(dir, test.name)
+		if test.data != nil {
+			if err := os.WriteFile(path, test.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		report := &reporting.Report{}
+		GetSMBIOSInfo(report, path)
+		if len(report.Errors) != 1 || !strings.HasPrefix(report.Errors[0], test.error) {
+			t.Errorf("%v: expected %v error, got %v", test.name, test.error, report.Errors)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package providers
+
+import (
+	"bufio"
+	"cloud-z/reporting"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func readMemInfo() (map[string]uint64, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result := map[string]uint64{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// MemAvailable:    5679780 kB
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) == 3 && fields[2] == "kB" {
+			value *= 1024
+		}
+		result[strings.TrimSuffix(fields[0], ":")] = value
+	}
+
+	return result, scanner.Err()
+}
+
+func readHugePagePools(node string, dir string) []reporting.HugePagePoolReport {
+	var pools []reporting.HugePagePoolReport
+
+	dirs, _ := filepath.Glob(filepath.Join(dir, "hugepages-*kB"))
+	for _, poolDir := range dirs {
+		pageSize, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(poolDir), "hugepages-"), "kB"), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		total, _ := readSysInt(filepath.Join(poolDir, "nr_hugepages"))
+		free, _ := readSysInt(filepath.Join(poolDir, "free_hugepages"))
+		surplus, _ := readSysInt(filepath.Join(poolDir, "surplus_hugepages"))
+
+		pools = append(pools, reporting.HugePagePoolReport{
+			Node:     node,
+			PageSize: pageSize * 1024,
+			Total:    total,
+			Free:     free,
+			Surplus:  surplus,
+		})
+	}
+
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].PageSize < pools[j].PageSize
+	})
+
+	return pools
+}
+
+func readSwaps() ([]reporting.SwapDeviceReport, error) {
+	f, err := os.Open("/proc/swaps")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var swaps []reporting.SwapDeviceReport
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // skip header
+	for scanner.Scan() {
+		// /dev/zram0    partition    4038652    0    100
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+
+		size, _ := strconv.ParseUint(fields[2], 10, 64)
+		used, _ := strconv.ParseUint(fields[3], 10, 64)
+		priority, _ := strconv.Atoi(fields[4])
+
+		swap := reporting.SwapDeviceReport{
+			Name:     redactMountPoint(unescapeMountInfo(fields[0])),
+			Type:     fields[1],
+			Size:     size * 1024,
+			Used:     used * 1024,
+			Priority: priority,
+		}
+
+		if strings.HasPrefix(fields[0], "/dev/zram") {
+			swap.Type = "zram"
+			swap.Compressor, _ = readSysSelected(filepath.Join("/sys/block", filepath.Base(fields[0]), "comp_algorithm"))
+		}
+
+		swaps = append(swaps, swap)
+	}
+
+	return swaps, scanner.Err()
+}
+
+func GetMemoryConfigInfo(report *reporting.Report) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+
+	memInfo, err := readMemInfo()
+	if err != nil {
+		report.AddError(fmt.Sprintf("Unable to read memory information: %v", err))
+	} else {
+		report.Memory.Available = memInfo["MemAvailable"]
+	}
+
+	config := &report.Memory.Config
+
+	config.HugePages = readHugePagePools("all", "/sys/kernel/mm/hugepages")
+	nodes, _ := filepath.Glob("/sys/devices/system/node/node[0-9]*")
+	for _, node := range nodes {
+		config.HugePages = append(config.HugePages, readHugePagePools(filepath.Base(node), filepath.Join(node, "hugepages"))...)
+	}
+
+	config.TransparentHugePages, _ = readSysSelected("/sys/kernel/mm/transparent_hugepage/enabled")
+	config.TransparentHugePagesDefrag, _ = readSysSelected("/sys/kernel/mm/transparent_hugepage/defrag")
+
+	if swappiness, err := readSysInt("/proc/sys/vm/swappiness"); err == nil {
+		config.Swappiness = int(swappiness)
+	}
+	if overcommit, err := readSysInt("/proc/sys/vm/overcommit_memory"); err == nil {
+		config.OvercommitMemory = int(overcommit)
+	}
+	if ratio, err := readSysInt("/proc/sys/vm/overcommit_ratio"); err == nil {
+		config.OvercommitRatio = int(ratio)
+	}
+
+	config.Swap, err = readSwaps()
+	if err != nil {
+		report.AddError(fmt.Sprintf("Unable to read swap devices: %v", err))
+	}
+
+	if zswap, err := readSysString("/sys/module/zswap/parameters/enabled"); err == nil {
+		config.Zswap.Enabled = zswap == "Y"
+		config.Zswap.Compressor, _ = readSysString("/sys/module/zswap/parameters/compressor")
+	}
+
+	if run, err := readSysInt("/sys/kernel/mm/ksm/run"); err == nil {
+		runStates := []string{"stopped", "running", "unmerging"}
+		if run >= 0 && int(run) < len(runStates) {
+			config.KSM.Run = runStates[run]
+		}
+		config.KSM.PagesShared, _ = readSysInt("/sys/kernel/mm/ksm/pages_shared")
+		config.KSM.PagesSharing, _ = readSysInt("/sys/kernel/mm/ksm/pages_sharing")
+	}
+}
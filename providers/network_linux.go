@@ -0,0 +1,214 @@
+package providers
+
+import (
+	"bytes"
+	"cloud-z/reporting"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+)
+
+const ethtoolStringSetStats = 1
+const ethtoolStringLength = 32
+
+// drivers that only drive SR-IOV virtual functions
+var vfDrivers = map[string]bool{
+	"ixgbevf": true,
+	"iavf":    true,
+	"i40evf":  true,
+}
+
+// PCI vendor and device ids of virtual functions whose drivers also drive physical functions. Instances don't see the
+// physical function, so there is no physfn link to tell them apart.
+var vfDeviceIds = map[string]bool{
+	// ENA and ENA with LLQ
+	"0x1d0f:0xec20": true,
+	"0x1d0f:0xec21": true,
+	// ConnectX-2 and ConnectX-3 with mlx4
+	"0x15b3:0x1002": true,
+	"0x15b3:0x1004": true,
+	// Connect-IB, ConnectX-4, ConnectX-4 Lx, ConnectX-5, ConnectX-5 Ex, ConnectX-6 and later with mlx5
+	"0x15b3:0x1012": true,
+	"0x15b3:0x1014": true,
+	"0x15b3:0x1016": true,
+	"0x15b3:0x1018": true,
+	"0x15b3:0x101a": true,
+	"0x15b3:0x101c": true,
+	"0x15b3:0x101e": true,
+}
+
+// ethtoolRequest is struct ifreq with the data pointer ethtool ioctls use.
+type ethtoolRequest struct {
+	name [unix.IFNAMSIZ]byte
+	data unsafe.Pointer
+	_    [16]byte
+}
+
+func ethtoolIoctl(fd int, name string, data unsafe.Pointer) error {
+	request := ethtoolRequest{data: data}
+	copy(request.name[:unix.IFNAMSIZ-1], name)
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.SIOCETHTOOL, uintptr(unsafe.Pointer(&request)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// ethtoolStats returns driver statistics like `ethtool -S` does.
+func ethtoolStats(fd int, name string, count uint32) (map[string]uint64, error) {
+	if count == 0 {
+		return nil, nil
+	}
+
+	// struct ethtool_gstrings is three u32 followed by count strings of 32 bytes
+	names := make([]uint32, 3+count*ethtoolStringLength/4)
+	names[0] = unix.ETHTOOL_GSTRINGS
+	names[1] = ethtoolStringSetStats
+	names[2] = count
+	if err := ethtoolIoctl(fd, name, unsafe.Pointer(&names[0])); err != nil {
+		return nil, err
+	}
+	nameBytes := unsafe.Slice((*byte)(unsafe.Pointer(&names[3])), count*ethtoolStringLength)
+
+	// struct ethtool_stats is two u32 followed by count u64 values
+	values := make([]uint64, 1+count)
+	header := (*[2]uint32)(unsafe.Pointer(&values[0]))
+	header[0] = unix.ETHTOOL_GSTATS
+	header[1] = count
+	if err := ethtoolIoctl(fd, name, unsafe.Pointer(&values[0])); err != nil {
+		return nil, err
+	}
+
+	stats := map[string]uint64{}
+	for i := uint32(0); i < count; i++ {
+		statName := nameBytes[i*ethtoolStringLength : (i+1)*ethtoolStringLength]
+		stats[cString(statName)] = values[1+i]
+	}
+	return stats, nil
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// interfaceVF checks if the interface is an SR-IOV virtual function.
+func interfaceVF(dir string) bool {
+	device := filepath.Join(dir, "device")
+	// only there when the physical function is visible too, like on bare metal
+	if _, err := os.Stat(filepath.Join(device, "physfn")); err == nil {
+		return true
+	}
+	if driver, err := os.Readlink(filepath.Join(device, "driver")); err == nil && vfDrivers[filepath.Base(driver)] {
+		return true
+	}
+	vendor, _ := readSysString(filepath.Join(device, "vendor"))
+	id, _ := readSysString(filepath.Join(device, "device"))
+	return vfDeviceIds[vendor+":"+id]
+}
+
+// interfaceSRIOV checks if the interface is a virtual function, has virtual functions, or is a synthetic interface
+// backed by one like Azure accelerated networking.
+func interfaceSRIOV(dir string) bool {
+	if interfaceVF(dir) {
+		return true
+	}
+	if vfs, err := readSysInt(filepath.Join(dir, "device", "sriov_numvfs")); err == nil && vfs > 0 {
+		return true
+	}
+	lowers, _ := filepath.Glob(filepath.Join(dir, "lower_*"))
+	for _, lower := range lowers {
+		if interfaceVF(lower) {
+			return true
+		}
+	}
+	return false
+}
+
+func readNetworkInterface(dir string, fd int) reporting.NetworkInterfaceReport {
+	name := filepath.Base(dir)
+	iface := reporting.NetworkInterfaceReport{
+		Name: name,
+	}
+
+	// MAC and IP addresses are left out on purpose
+	if driver, err := os.Readlink(filepath.Join(dir, "device", "driver")); err == nil {
+		iface.Driver = filepath.Base(driver)
+	}
+	iface.State, _ = readSysString(filepath.Join(dir, "operstate"))
+	if speed, err := readSysInt(filepath.Join(dir, "speed")); err == nil && speed > 0 {
+		// -1 or an error when the link is down or the driver doesn't know
+		iface.Speed = int(speed)
+	}
+	if mtu, err := readSysInt(filepath.Join(dir, "mtu")); err == nil {
+		iface.MTU = int(mtu)
+	}
+	rxQueues, _ := filepath.Glob(filepath.Join(dir, "queues", "rx-*"))
+	iface.RxQueues = len(rxQueues)
+	txQueues, _ := filepath.Glob(filepath.Join(dir, "queues", "tx-*"))
+	iface.TxQueues = len(txQueues)
+	iface.SRIOV = interfaceSRIOV(dir)
+
+	if fd >= 0 {
+		if info, err := unix.IoctlGetEthtoolDrvinfo(fd, name); err == nil {
+			iface.DriverVersion = cString(info.Version[:])
+			iface.Firmware = cString(info.Fw_version[:])
+			if iface.Driver == "ena" {
+				// ENA Express is only visible in driver statistics
+				stats, _ := ethtoolStats(fd, name, info.N_stats)
+				iface.ENAExpress = stats["ena_srd_mode"] != 0
+			}
+		}
+	}
+	if iface.DriverVersion == "" && iface.Driver != "" {
+		iface.DriverVersion, _ = readSysString(filepath.Join("/sys/module", iface.Driver, "version"))
+	}
+
+	return iface
+}
+
+// countEFADevices counts Elastic Fabric Adapters which show up as InfiniBand devices and not as network interfaces.
+func countEFADevices() int {
+	devices, _ := filepath.Glob("/sys/class/infiniband/*")
+	count := 0
+	for _, device := range devices {
+		driver, err := os.Readlink(filepath.Join(device, "device", "driver"))
+		if err == nil && filepath.Base(driver) == "efa" {
+			count++
+		}
+	}
+	return count
+}
+
+func GetNetworkInfo(report *reporting.Report) {
+	dirs, err := filepath.Glob("/sys/class/net/*")
+	if err != nil {
+		report.AddError(fmt.Sprintf("Unable to list network interfaces: %v", err))
+		return
+	}
+
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		// driver versions will come from sysfs
+		fd = -1
+	} else {
+		defer unix.Close(fd)
+	}
+
+	for _, dir := range dirs {
+		// skip loopback, bridges, veth pairs, tunnels and other interfaces with no hardware behind them
+		path, err := filepath.EvalSymlinks(dir)
+		if err != nil || strings.Contains(path, "/devices/virtual/") {
+			continue
+		}
+
+		report.Network.Interfaces = append(report.Network.Interfaces, readNetworkInterface(dir, fd))
+	}
+
+	report.Network.EFADevices = countEFADevices()
+}
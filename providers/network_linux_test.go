@@ -0,0 +1,93 @@
+package providers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeInterface creates a /sys/class/net style directory for an interface on a PCI device.
+func fakeInterface(t *testing.T, root string, name string, driver string, vendor string, device string, physfn bool) string {
+	dir := filepath.Join(root, name)
+	deviceDir := filepath.Join(root, "devices", name)
+	for _, d := range []string{dir, deviceDir, filepath.Join(root, "drivers", driver)} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	links := map[string]string{
+		filepath.Join(dir, "device"):       deviceDir,
+		filepath.Join(deviceDir, "driver"): filepath.Join(root, "drivers", driver),
+	}
+	if physfn {
+		links[filepath.Join(deviceDir, "physfn")] = root
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := map[string]string{
+		filepath.Join(deviceDir, "vendor"): vendor + "\n",
+		filepath.Join(deviceDir, "device"): device + "\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestInterfaceSRIOV(t *testing.T) {
+	root := t.TempDir()
+
+	tests := []struct {
+		name   string
+		driver string
+		vendor string
+		device string
+		physfn bool
+		want   bool
+	}{
+		{"ena-vf", "ena", "0x1d0f", "0xec20", false, true},
+		{"ena-llq-vf", "ena", "0x1d0f", "0xec21", false, true},
+		{"ena-pf", "ena", "0x1d0f", "0x0ec2", false, false},
+		{"mlx5-vf", "mlx5_core", "0x15b3", "0x1016", false, true},
+		{"mlx5-pf", "mlx5_core", "0x15b3", "0x1017", false, false},
+		{"mlx5-vf-on-host", "mlx5_core", "0x15b3", "0x1017", true, true},
+		{"ixgbevf", "ixgbevf", "0x8086", "0x10ed", false, true},
+		{"virtio", "virtio_net", "0x1af4", "0x1000", false, false},
+	}
+
+	for _, test := range tests {
+		dir := fakeInterface(t, root, test.name, test.driver, test.vendor, test.device, test.physfn)
+		if got := interfaceSRIOV(dir); got != test.want {
+			t.Errorf("%v: expected %v but got %v", test.name, test.want, got)
+		}
+	}
+
+	// Azure accelerated networking puts a synthetic interface on top of the virtual function
+	synthetic := fakeInterface(t, root, "synthetic", "hv_netvsc", "", "", false)
+	if interfaceSRIOV(synthetic) {
+		t.Error("synthetic interface without a lower interface is SR-IOV")
+	}
+	if err := os.Symlink(filepath.Join(root, "mlx5-vf"), filepath.Join(synthetic, "lower_mlx5-vf")); err != nil {
+		t.Fatal(err)
+	}
+	if !interfaceSRIOV(synthetic) {
+		t.Error("synthetic interface on a virtual function isn't SR-IOV")
+	}
+
+	// a physical function with virtual functions enabled
+	pf := fakeInterface(t, root, "pf", "mlx5_core", "0x15b3", "0x1017", false)
+	if err := os.WriteFile(filepath.Join(pf, "device", "sriov_numvfs"), []byte("4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !interfaceSRIOV(pf) {
+		t.Error("physical function with virtual functions isn't SR-IOV")
+	}
+}
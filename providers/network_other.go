@@ -0,0 +1,8 @@
+//go:build !linux
+
+package providers
+
+import "cloud-z/reporting"
+
+func GetNetworkInfo(report *reporting.Report) {
+}
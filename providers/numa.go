@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"cloud-z/reporting"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseCPUList parses kernel CPU lists like "0-3,8-11".
+func parseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(list, ",") {
+		if part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil {
+				return nil, err
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+func readNumaNode(dir string) (reporting.NumaNodeReport, error) {
+	node := reporting.NumaNodeReport{}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+	if err != nil {
+		return node, err
+	}
+	node.Id = id
+
+	cpuList, err := readSysString(filepath.Join(dir, "cpulist"))
+	if err != nil {
+		return node, err
+	}
+	node.CPUs, err = parseCPUList(cpuList)
+	if err != nil {
+		return node, err
+	}
+
+	distances, err := readSysString(filepath.Join(dir, "distance"))
+	if err != nil {
+		return node, err
+	}
+	for _, distance := range strings.Fields(distances) {
+		value, err := strconv.Atoi(distance)
+		if err != nil {
+			return node, err
+		}
+		node.Distance = append(node.Distance, value)
+	}
+
+	// Node 0 MemTotal:        6158152 kB
+	memInfo, _ := readSysString(filepath.Join(dir, "meminfo"))
+	for _, line := range strings.Split(memInfo, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 4 && fields[2] == "MemTotal:" {
+			memTotal, _ := strconv.ParseUint(fields[3], 10, 64)
+			node.Memory = memTotal * 1024
+		}
+	}
+
+	return node, nil
+}
+
+func GetNumaInfo(report *reporting.Report) {
+	dirs, _ := filepath.Glob("/sys/devices/system/node/node[0-9]*")
+
+	var nodes []reporting.NumaNodeReport
+	for _, dir := range dirs {
+		node, err := readNumaNode(dir)
+		if err != nil {
+			report.AddError(fmt.Sprintf("Unable to read NUMA node %v: %v", filepath.Base(dir), err))
+			return
+		}
+		nodes = append(nodes, node)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Id < nodes[j].Id
+	})
+
+	report.Numa.Nodes = nodes
+}
@@ -0,0 +1,40 @@
+package providers
+
+import (
+	"strconv"
+	"strings"
+)
+
+// userMountRoots have subdirectories named after users or the volumes they plug in
+var userMountRoots = []string{"/home/", "/var/home/", "/media/", "/run/media/", "/run/user/"}
+
+// unescapeMountInfo decodes the octal escapes mountinfo and /proc/swaps use for spaces and other special characters.
+func unescapeMountInfo(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var result strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if value, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				result.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+		result.WriteByte(s[i])
+	}
+	return result.String()
+}
+
+// redactMountPoint hides user names and volume labels, so /home/alice/data becomes /home/<redacted>. It's used for
+// swap files too.
+func redactMountPoint(mountPoint string) string {
+	for _, root := range userMountRoots {
+		if strings.HasPrefix(mountPoint, root) && len(mountPoint) > len(root) {
+			return root + "<redacted>"
+		}
+	}
+	return mountPoint
+}
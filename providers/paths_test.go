@@ -0,0 +1,42 @@
+package providers
+
+import "testing"
+
+func TestRedactMountPoint(t *testing.T) {
+	tests := map[string]string{
+		"/":                         "/",
+		"/home":                     "/home",
+		"/home/":                    "/home/",
+		"/home/alice":               "/home/<redacted>",
+		"/home/alice/projects/x":    "/home/<redacted>",
+		"/var/home/alice":           "/var/home/<redacted>",
+		"/media/alice/USB STICK":    "/media/<redacted>",
+		"/run/media/alice/BACKUP":   "/run/media/<redacted>",
+		"/run/user/1000/doc":        "/run/user/<redacted>",
+		"/mnt/data":                 "/mnt/data",
+		"/var/lib/docker/overlay2/": "/var/lib/docker/overlay2/",
+		"/home/alice/swapfile":      "/home/<redacted>",
+		"/swapfile":                 "/swapfile",
+	}
+
+	for mountPoint, want := range tests {
+		if got := redactMountPoint(mountPoint); got != want {
+			t.Errorf("%v: expected %v but got %v", mountPoint, want, got)
+		}
+	}
+}
+
+func TestUnescapeMountInfo(t *testing.T) {
+	tests := map[string]string{
+		"/mnt/data":                 "/mnt/data",
+		`/media/alice/USB\040STICK`: "/media/alice/USB STICK",
+		`/home/alice/swap\011file`:  "/home/alice/swap\tfile",
+		`/mnt/trailing\04`:          `/mnt/trailing\04`,
+	}
+
+	for escaped, want := range tests {
+		if got := unescapeMountInfo(escaped); got != want {
+			t.Errorf("%v: expected %q but got %q", escaped, want, got)
+		}
+	}
+}
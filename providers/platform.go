@@ -0,0 +1,327 @@
+package providers
+
+import (
+	"bytes"
+	"cloud-z/reporting"
+	"encoding/binary"
+	"fmt"
+	"github.com/digitalocean/go-smbios/smbios"
+)
+
+// https://www.dmtf.org/sites/default/files/standards/documents/DSP0134_3.1.1.pdf
+type BIOSInformation struct {
+	Vendor                 string
+	Version                string
+	StartingAddressSegment uint16
+	ReleaseDate            string
+	ROMSize                uint8
+	Characteristics        uint64
+}
+
+type SystemInformation struct {
+	Manufacturer string
+	ProductName  string
+	Version      string
+	SerialNumber string
+	UUID         [16]byte
+	WakeUpType   uint8
+	SKUNumber    string
+	Family       string
+}
+
+type BaseboardInformation struct {
+	Manufacturer string
+	Product      string
+	Version      string
+	SerialNumber string
+	AssetTag     string
+}
+
+type ProcessorInformation struct {
+	SocketDesignation string
+	ProcessorType     uint8
+	ProcessorFamily   uint8
+	Manufacturer      string
+	ProcessorId       uint64
+	Version           string
+	Voltage           uint8
+	ExternalClock     uint16
+	MaxSpeed          uint16
+	CurrentSpeed      uint16
+	Status            uint8
+	Upgrade           uint8
+	L1CacheHandle     uint16
+	L2CacheHandle     uint16
+	L3CacheHandle     uint16
+	SerialNumber      string
+	AssetTag          string
+	PartNumber        string
+	CoreCount         uint8
+	CoreEnabled       uint8
+	ThreadCount       uint8
+	Characteristics   uint16
+	ProcessorFamily2  uint16
+	CoreCount2        uint16
+	CoreEnabled2      uint16
+	ThreadCount2      uint16
+}
+
+type PhysicalMemoryArray struct {
+	Location                     uint8
+	Use                          uint8
+	MemoryErrorCorrection        uint8
+	MaximumCapacity              uint32
+	MemoryErrorInformationHandle uint16
+	NumberOfMemoryDevices        uint16
+	ExtendedMaximumCapacity      uint64
+}
+
+type MemoryArrayMappedAddress struct {
+	StartingAddress         uint32
+	EndingAddress           uint32
+	MemoryArrayHandle       uint16
+	PartitionWidth          uint8
+	ExtendedStartingAddress uint64
+	ExtendedEndingAddress   uint64
+}
+
+func (proc *ProcessorInformation) coreCount() int {
+	if proc.CoreCount == 0xff {
+		return int(proc.CoreCount2)
+	}
+	return int(proc.CoreCount)
+}
+
+func (proc *ProcessorInformation) coreEnabled() int {
+	if proc.CoreEnabled == 0xff {
+		return int(proc.CoreEnabled2)
+	}
+	return int(proc.CoreEnabled)
+}
+
+func (proc *ProcessorInformation) threadCount() int {
+	if proc.ThreadCount == 0xff {
+		return int(proc.ThreadCount2)
+	}
+	return int(proc.ThreadCount)
+}
+
+func (array *PhysicalMemoryArray) use() string {
+	uses := []string{
+		"<BAD VALUE>",
+		"Other",
+		"Unknown",
+		"System memory",
+		"Video memory",
+		"Flash memory",
+		"Non-volatile RAM",
+		"Cache memory",
+	}
+
+	if int(array.Use) >= len(uses) {
+		return "<BAD VALUE>"
+	}
+
+	return uses[array.Use]
+}
+
+func (array *PhysicalMemoryArray) errorCorrection() string {
+	types := []string{
+		"<BAD VALUE>",
+		"Other",
+		"Unknown",
+		"None",
+		"Parity",
+		"Single-bit ECC",
+		"Multi-bit ECC",
+		"CRC",
+	}
+
+	if int(array.MemoryErrorCorrection) >= len(types) {
+		return "<BAD VALUE>"
+	}
+
+	return types[array.MemoryErrorCorrection]
+}
+
+func (array *PhysicalMemoryArray) maximumCapacity() uint64 {
+	if array.MaximumCapacity == 0x80000000 {
+		return array.ExtendedMaximumCapacity
+	}
+	return uint64(array.MaximumCapacity) * 1024
+}
+
+func (mapped *MemoryArrayMappedAddress) addressRange() (uint64, uint64) {
+	if mapped.StartingAddress == 0xffffffff {
+		return mapped.ExtendedStartingAddress, mapped.ExtendedEndingAddress
+	}
+	return uint64(mapped.StartingAddress) * 1024, uint64(mapped.EndingAddress)*1024 + 1023
+}
+
+func getPlatformInfo(report *reporting.Report, records []*smbios.Structure) {
+	for _, record := range records {
+		switch record.Header.Type {
+		case 0:
+			bios := readBIOSInformation(record)
+			report.Platform.BIOS = reporting.BiosReport{
+				Vendor:      bios.Vendor,
+				Version:     bios.Version,
+				ReleaseDate: bios.ReleaseDate,
+			}
+		case 1:
+			// serial number and UUID are PII and are left out
+			system := readSystemInformation(record)
+			report.Platform.System = reporting.SystemReport{
+				Manufacturer: system.Manufacturer,
+				ProductName:  system.ProductName,
+				Version:      system.Version,
+				SKU:          system.SKUNumber,
+				Family:       system.Family,
+			}
+		case 2:
+			// serial number and asset tag are PII and are left out
+			baseboard := readBaseboardInformation(record)
+			report.Platform.Baseboard = reporting.BaseboardReport{
+				Manufacturer: baseboard.Manufacturer,
+				Product:      baseboard.Product,
+				Version:      baseboard.Version,
+			}
+		case 4:
+			proc := readProcessorInformation(record)
+			report.Platform.Processors = append(report.Platform.Processors, reporting.ProcessorSocketReport{
+				Socket:        proc.SocketDesignation,
+				Manufacturer:  proc.Manufacturer,
+				Version:       proc.Version,
+				ExternalClock: proc.ExternalClock,
+				MaxSpeed:      proc.MaxSpeed,
+				CurrentSpeed:  proc.CurrentSpeed,
+				CoreCount:     proc.coreCount(),
+				CoreEnabled:   proc.coreEnabled(),
+				ThreadCount:   proc.threadCount(),
+			})
+		case 16:
+			array := readPhysicalMemoryArray(record)
+			report.Platform.MemoryArrays = append(report.Platform.MemoryArrays, reporting.MemoryArrayReport{
+				Use:             array.use(),
+				ErrorCorrection: array.errorCorrection(),
+				MaximumCapacity: array.maximumCapacity(),
+				Devices:         array.NumberOfMemoryDevices,
+			})
+		case 19:
+			mapped := readMemoryArrayMappedAddress(record)
+			start, end := mapped.addressRange()
+			report.Platform.MappedAddressRanges = append(report.Platform.MappedAddressRanges, reporting.MappedAddressRangeReport{
+				Start:          fmt.Sprintf("0x%x", start),
+				End:            fmt.Sprintf("0x%x", end),
+				Size:           end - start + 1,
+				PartitionWidth: mapped.PartitionWidth,
+			})
+		}
+	}
+}
+
+func readBIOSInformation(record *smbios.Structure) BIOSInformation {
+	bios := BIOSInformation{}
+	recordBytes := bytes.NewReader(record.Formatted)
+
+	bios.Vendor = readString(recordBytes, record.Strings)
+	bios.Version = readString(recordBytes, record.Strings)
+	binary.Read(recordBytes, binary.LittleEndian, &bios.StartingAddressSegment)
+	bios.ReleaseDate = readString(recordBytes, record.Strings)
+	binary.Read(recordBytes, binary.LittleEndian, &bios.ROMSize)
+	binary.Read(recordBytes, binary.LittleEndian, &bios.Characteristics)
+
+	return bios
+}
+
+func readSystemInformation(record *smbios.Structure) SystemInformation {
+	system := SystemInformation{}
+	recordBytes := bytes.NewReader(record.Formatted)
+
+	system.Manufacturer = readString(recordBytes, record.Strings)
+	system.ProductName = readString(recordBytes, record.Strings)
+	system.Version = readString(recordBytes, record.Strings)
+	system.SerialNumber = readString(recordBytes, record.Strings)
+	binary.Read(recordBytes, binary.LittleEndian, &system.UUID)
+	binary.Read(recordBytes, binary.LittleEndian, &system.WakeUpType)
+	system.SKUNumber = readString(recordBytes, record.Strings)
+	system.Family = readString(recordBytes, record.Strings)
+
+	return system
+}
+
+func readBaseboardInformation(record *smbios.Structure) BaseboardInformation {
+	baseboard := BaseboardInformation{}
+	recordBytes := bytes.NewReader(record.Formatted)
+
+	baseboard.Manufacturer = readString(recordBytes, record.Strings)
+	baseboard.Product = readString(recordBytes, record.Strings)
+	baseboard.Version = readString(recordBytes, record.Strings)
+	baseboard.SerialNumber = readString(recordBytes, record.Strings)
+	baseboard.AssetTag = readString(recordBytes, record.Strings)
+
+	return baseboard
+}
+
+func readProcessorInformation(record *smbios.Structure) ProcessorInformation {
+	proc := ProcessorInformation{}
+	recordBytes := bytes.NewReader(record.Formatted)
+
+	proc.SocketDesignation = readString(recordBytes, record.Strings)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.ProcessorType)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.ProcessorFamily)
+	proc.Manufacturer = readString(recordBytes, record.Strings)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.ProcessorId)
+	proc.Version = readString(recordBytes, record.Strings)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.Voltage)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.ExternalClock)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.MaxSpeed)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.CurrentSpeed)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.Status)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.Upgrade)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.L1CacheHandle)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.L2CacheHandle)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.L3CacheHandle)
+	proc.SerialNumber = readString(recordBytes, record.Strings)
+	proc.AssetTag = readString(recordBytes, record.Strings)
+	proc.PartNumber = readString(recordBytes, record.Strings)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.CoreCount)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.CoreEnabled)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.ThreadCount)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.Characteristics)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.ProcessorFamily2)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.CoreCount2)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.CoreEnabled2)
+	binary.Read(recordBytes, binary.LittleEndian, &proc.ThreadCount2)
+
+	return proc
+}
+
+func readPhysicalMemoryArray(record *smbios.Structure) PhysicalMemoryArray {
+	array := PhysicalMemoryArray{}
+	recordBytes := bytes.NewReader(record.Formatted)
+
+	binary.Read(recordBytes, binary.LittleEndian, &array.Location)
+	binary.Read(recordBytes, binary.LittleEndian, &array.Use)
+	binary.Read(recordBytes, binary.LittleEndian, &array.MemoryErrorCorrection)
+	binary.Read(recordBytes, binary.LittleEndian, &array.MaximumCapacity)
+	binary.Read(recordBytes, binary.LittleEndian, &array.MemoryErrorInformationHandle)
+	binary.Read(recordBytes, binary.LittleEndian, &array.NumberOfMemoryDevices)
+	binary.Read(recordBytes, binary.LittleEndian, &array.ExtendedMaximumCapacity)
+
+	return array
+}
+
+func readMemoryArrayMappedAddress(record *smbios.Structure) MemoryArrayMappedAddress {
+	mapped := MemoryArrayMappedAddress{}
+	recordBytes := bytes.NewReader(record.Formatted)
+
+	binary.Read(recordBytes, binary.LittleEndian, &mapped.StartingAddress)
+	binary.Read(recordBytes, binary.LittleEndian, &mapped.EndingAddress)
+	binary.Read(recordBytes, binary.LittleEndian, &mapped.MemoryArrayHandle)
+	binary.Read(recordBytes, binary.LittleEndian, &mapped.PartitionWidth)
+	binary.Read(recordBytes, binary.LittleEndian, &mapped.ExtendedStartingAddress)
+	binary.Read(recordBytes, binary.LittleEndian, &mapped.ExtendedEndingAddress)
+
+	return mapped
+}
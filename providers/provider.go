@@ -1,8 +1,13 @@
 package providers
 
-import "cloud-z/reporting"
+import (
+	"cloud-z/reporting"
+	"context"
+)
 
 type CloudProvider interface {
 	Detect() bool
 	GetData(*reporting.Report)
+	// ProbeMetadata makes a single small metadata request to measure metadata service latency
+	ProbeMetadata(ctx context.Context) error
 }
@@ -0,0 +1,114 @@
+package providers
+
+import (
+	"cloud-z/reporting"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+func blockTransport(name string) string {
+	switch {
+	case strings.HasPrefix(name, "nvme"):
+		return "nvme"
+	case strings.HasPrefix(name, "xvd"):
+		return "xen"
+	case strings.HasPrefix(name, "vd"):
+		return "virtio"
+	case strings.HasPrefix(name, "sd"):
+		return "scsi"
+	}
+	return "unknown"
+}
+
+// blockKind guesses what kind of cloud volume a disk is based on the model strings each cloud uses.
+func blockKind(transport string, vendor string, model string) string {
+	switch {
+	case model == "Amazon Elastic Block Store":
+		return "ebs"
+	case model == "Amazon EC2 NVMe Instance Storage":
+		return "instance-store"
+	case transport == "xen":
+		// older Xen instances expose both EBS and instance store as blkfront devices
+		return "xen-blkfront"
+	case model == "PersistentDisk" || model == "nvme_card-pd":
+		return "gcp-persistent-disk"
+	case model == "EphemeralDisk" || model == "nvme_card":
+		return "gcp-local-ssd"
+	case vendor == "Msft" && model == "Virtual Disk", strings.HasPrefix(model, "MSFT NVMe Accelerator"):
+		return "azure-managed-disk"
+	case model == "Microsoft NVMe Direct Disk":
+		return "azure-local-nvme"
+	}
+	return "unknown"
+}
+
+func readBlockDevice(dir string) reporting.StorageDeviceReport {
+	name := filepath.Base(dir)
+	device := reporting.StorageDeviceReport{
+		Name:      name,
+		Transport: blockTransport(name),
+	}
+
+	// serial numbers are left out as they hold volume ids on most clouds
+	device.Vendor, _ = readSysString(filepath.Join(dir, "device", "vendor"))
+	device.Model, _ = readSysString(filepath.Join(dir, "device", "model"))
+	device.Firmware, _ = readSysString(filepath.Join(dir, "device", "firmware_rev"))
+	if device.Firmware == "" {
+		device.Firmware, _ = readSysString(filepath.Join(dir, "device", "rev"))
+	}
+	device.Kind = blockKind(device.Transport, device.Vendor, device.Model)
+
+	if sectors, err := readSysInt(filepath.Join(dir, "size")); err == nil {
+		// always in 512 byte sectors no matter the real sector size
+		device.Size = uint64(sectors) * 512
+	}
+	if rotational, err := readSysInt(filepath.Join(dir, "queue", "rotational")); err == nil {
+		device.Rotational = rotational == 1
+	}
+	if logical, err := readSysInt(filepath.Join(dir, "queue", "logical_block_size")); err == nil {
+		device.LogicalSectorSize = int(logical)
+	}
+	if physical, err := readSysInt(filepath.Join(dir, "queue", "physical_block_size")); err == nil {
+		device.PhysicalSectorSize = int(physical)
+	}
+	device.Scheduler, _ = readSysSelected(filepath.Join(dir, "queue", "scheduler"))
+	if requests, err := readSysInt(filepath.Join(dir, "queue", "nr_requests")); err == nil {
+		device.QueueDepth = int(requests)
+	}
+	if depth, err := readSysInt(filepath.Join(dir, "device", "queue_depth")); err == nil {
+		// SCSI devices have their own limit that is usually lower
+		device.QueueDepth = int(depth)
+	}
+	hardwareQueues, _ := filepath.Glob(filepath.Join(dir, "mq", "[0-9]*"))
+	device.HardwareQueues = len(hardwareQueues)
+
+	return device
+}
+
+func GetStorageInfo(report *reporting.Report) {
+	if runtime.GOOS != "linux" {
+		return
+	}
+
+	dirs, err := filepath.Glob("/sys/block/*")
+	if err != nil {
+		report.AddError(fmt.Sprintf("Unable to list block devices: %v", err))
+		return
+	}
+
+	for _, dir := range dirs {
+		// skip loop, ram, zram, device mapper and other devices with no hardware behind them
+		path, err := filepath.EvalSymlinks(dir)
+		if err != nil || strings.Contains(path, "/devices/virtual/") {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, "device")); err != nil {
+			continue
+		}
+
+		report.Storage.Devices = append(report.Storage.Devices, readBlockDevice(dir))
+	}
+}
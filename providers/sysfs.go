@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+func readSysString(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func readSysInt(path string) (int64, error) {
+	value, err := readSysString(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// readSysSelected returns the selected option out of a sysfs option list like "always [madvise] never".
+func readSysSelected(path string) (string, error) {
+	value, err := readSysString(path)
+	if err != nil {
+		return "", err
+	}
+
+	start := strings.Index(value, "[")
+	end := strings.Index(value, "]")
+	if start == -1 || end < start {
+		return value, nil
+	}
+
+	return value[start+1 : end], nil
+}
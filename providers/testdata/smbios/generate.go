@@ -0,0 +1,226 @@
+//go:build ignore
+
+// Generates the synthetic SMBIOS dumps in this directory, the edge-*.bin files. Run with `go run generate.go` from this
+// directory.
+//
+// These tables cover values that real dumps rarely have, like string indexes of 0, 0xff core counts, extended sizes
+// and memory mapped above 4GB through extended addresses, and both entry point layouts of dmidecode --dump-bin.
+// Everything else is tested with real dumps scrubbed with scrub.go, see README.md.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+)
+
+type table struct {
+	buffer bytes.Buffer
+	count  int
+	handle uint16
+}
+
+// str is a string index in a formatted area, 0 means no string
+type str uint8
+
+// stringSet collects the strings of one structure. Empty strings get index 0 as they can't be stored.
+type stringSet []string
+
+func (set *stringSet) index(s string) str {
+	if s == "" {
+		return 0
+	}
+	*set = append(*set, s)
+	return str(len(*set))
+}
+
+func (t *table) add(structureType uint8, fields []any, strings ...string) {
+	var formatted bytes.Buffer
+	for _, field := range fields {
+		_ = binary.Write(&formatted, binary.LittleEndian, field)
+	}
+
+	t.buffer.WriteByte(structureType)
+	t.buffer.WriteByte(uint8(4 + formatted.Len()))
+	_ = binary.Write(&t.buffer, binary.LittleEndian, t.handle)
+	t.buffer.Write(formatted.Bytes())
+	for _, s := range strings {
+		t.buffer.WriteString(s)
+		t.buffer.WriteByte(0)
+	}
+	if len(strings) == 0 {
+		t.buffer.WriteByte(0)
+	}
+	t.buffer.WriteByte(0)
+
+	t.count++
+	t.handle++
+}
+
+func (t *table) end() []byte {
+	t.add(127, nil)
+	return t.buffer.Bytes()
+}
+
+func bios(t *table, vendor, version, date string) {
+	var set stringSet
+	t.add(0, []any{set.index(vendor), set.index(version), uint16(0xe800), set.index(date), uint8(0), uint64(0x08), uint16(0x0c03), uint8(0), uint8(0), uint8(0xff), uint8(0xff)},
+		set...)
+}
+
+func system(t *table, manufacturer, product, version, serial, sku, family string) {
+	var set stringSet
+	uuid := [16]byte{0xec, 0x2a, 0x1b, 0x3c, 0x4d, 0x5e, 0x6f, 0x70, 0x81, 0x92, 0xa3, 0xb4, 0xc5, 0xd6, 0xe7, 0xf8}
+	t.add(1, []any{set.index(manufacturer), set.index(product), set.index(version), set.index(serial), uuid, uint8(6), set.index(sku), set.index(family)},
+		set...)
+}
+
+func baseboard(t *table, manufacturer, product, version, serial, assetTag string) {
+	var set stringSet
+	t.add(2, []any{set.index(manufacturer), set.index(product), set.index(version), set.index(serial), set.index(assetTag), uint8(0x01), str(0), uint16(0), uint8(0x0a), uint8(0)},
+		set...)
+}
+
+type processor struct {
+	socket, manufacturer, version         string
+	family                                uint8
+	family2                               uint16
+	externalClock, maxSpeed, currentSpeed uint16
+	cores, enabled, threads               uint16
+}
+
+func (p processor) add(t *table) {
+	count8 := func(count uint16) uint8 {
+		if count > 0xfe {
+			return 0xff
+		}
+		return uint8(count)
+	}
+	var set stringSet
+	t.add(4, []any{
+		set.index(p.socket), uint8(3), p.family, set.index(p.manufacturer), uint64(0x000306f2bfebfbff), set.index(p.version), uint8(0x80 | 12),
+		p.externalClock, p.maxSpeed, p.currentSpeed, uint8(0x41), uint8(1),
+		uint16(0xffff), uint16(0xffff), uint16(0xffff), set.index("Not Specified"), set.index("Not Specified"), set.index("Not Specified"),
+		count8(p.cores), count8(p.enabled), count8(p.threads), uint16(0x00fc), p.family2,
+		p.cores, p.enabled, p.threads,
+	}, set...)
+}
+
+func memoryArray(t *table, use, errorCorrection uint8, maximumKB uint32, extendedMaximum uint64, devices uint16) {
+	t.add(16, []any{uint8(3), use, errorCorrection, maximumKB, uint16(0xfffe), devices, extendedMaximum})
+}
+
+type memoryDevice struct {
+	locator, bank                        string
+	totalWidth, dataWidth, size          uint16
+	formFactor, memoryType               uint8
+	speed, configuredSpeed               uint16
+	manufacturer, serial, assetTag, part string
+	extendedSize                         uint32
+	voltage                              uint16
+	// short stops after the SMBIOS 2.3 fields like old hypervisors do
+	short bool
+}
+
+func (d memoryDevice) add(t *table) {
+	var set stringSet
+	fields := []any{
+		uint16(0x1000), uint16(0xfffe), d.totalWidth, d.dataWidth, d.size, d.formFactor, uint8(0), set.index(d.locator), set.index(d.bank),
+		d.memoryType, uint16(0x0080), d.speed, set.index(d.manufacturer), set.index(d.serial), set.index(d.assetTag), set.index(d.part),
+	}
+	if !d.short {
+		fields = append(fields, uint8(1), d.extendedSize, d.configuredSpeed, d.voltage, d.voltage, d.voltage)
+	}
+	t.add(17, fields, set...)
+}
+
+func mappedAddress(t *table, startKB, endKB uint32, extendedStart, extendedEnd uint64) {
+	t.add(19, []any{startKB, endKB, uint16(0x1000), uint8(1), extendedStart, extendedEnd})
+}
+
+// entryPoint32 returns a dmidecode --dump-bin file with a 32-bit entry point and the table right after it.
+func entryPoint32(tbl []byte, count int) []byte {
+	ep := make([]byte, 0x20)
+	copy(ep, "_SM_")
+	ep[5] = 0x1f
+	ep[6], ep[7] = 2, 7
+	binary.LittleEndian.PutUint16(ep[8:], 0x100)
+	copy(ep[16:], "_DMI_")
+	binary.LittleEndian.PutUint16(ep[22:], uint16(len(tbl)))
+	binary.LittleEndian.PutUint32(ep[24:], 0x20)
+	binary.LittleEndian.PutUint16(ep[28:], uint16(count))
+	ep[30] = 0x27
+	ep[21] = checksum(ep[16:31])
+	ep[4] = checksum(ep[:0x1f])
+	return append(ep, tbl...)
+}
+
+// entryPoint64 returns a dmidecode --dump-bin file with a 64-bit entry point and the table right after it.
+func entryPoint64(tbl []byte) []byte {
+	ep := make([]byte, 0x20)
+	copy(ep, "_SM3_")
+	ep[6] = 0x18
+	ep[7], ep[8], ep[10] = 3, 0, 1
+	binary.LittleEndian.PutUint32(ep[12:], uint32(len(tbl)))
+	binary.LittleEndian.PutUint64(ep[16:], 0x20)
+	ep[5] = checksum(ep[:0x18])
+	return append(ep, tbl...)
+}
+
+func checksum(b []byte) byte {
+	var sum byte
+	for _, v := range b {
+		sum += v
+	}
+	return -sum
+}
+
+func write(name string, data []byte) {
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		panic(err)
+	}
+}
+
+func main() {
+	// Values that used to break decoding: extended sizes, sizes in KB, types and form factors past the end of the
+	// known lists, and more than 254 cores
+	t := &table{}
+	bios(t, "Vendor", "Version", "01/01/2024")
+	system(t, "Manufacturer", "Product", "", "", "", "")
+	baseboard(t, "", "", "", "", "")
+	processor{"Socket 0", "Vendor", "Many Core CPU", 0xfe, 0x200, 200, 3700, 2400, 384, 384, 768}.add(t)
+	memoryArray(t, 3, 6, 0x80000000, 2*1024*1024*1024*1024, 3)
+	memoryDevice{"DIMM A", "BANK 0", 72, 64, 0x7fff, 0x10, 0x22, 4800, 4400, "Vendor", "12345678", "", "PART", 65536, 1100, false}.add(t)
+	memoryDevice{"DIMM B", "BANK 1", 72, 64, 0x8000 | 512, 0x0f, 0x1f, 4800, 4400, "Vendor", "87654321", "", "PART", 0, 1100, false}.add(t)
+	memoryDevice{"DIMM C", "BANK 2", 72, 64, 0, 0x02, 0x02, 0, 0, "", "", "", "", 0, 0, false}.add(t)
+	write("edge-cases.bin", t.end())
+
+	// What hypervisors tend to leave out: empty version, SKU and family strings, a memory device that stops after
+	// the SMBIOS 2.3 fields, unknown widths, an extended maximum capacity and memory split in several ranges. Written
+	// with a 32-bit entry point.
+	t = &table{}
+	bios(t, "Hypervisor", "1.0", "01/01/2024")
+	system(t, "Hypervisor", "Virtual Machine", "", "VM-SERIAL-0123456789", "", "")
+	baseboard(t, "Hypervisor", "Virtual Machine", "", "BOARD-SERIAL-0123456789", "ASSET-0123456789")
+	processor{"CPU 0", "Vendor", "Virtual CPU", 0xb3, 0xb3, 0, 2000, 2000, 1, 1, 2}.add(t)
+	memoryArray(t, 3, 3, 0x80000000, 1024*1024*1024*1024, 3)
+	memoryDevice{"DIMM 0", "", 64, 64, 4096, 0x09, 0x07, 0, 0, "Hypervisor", "", "", "", 0, 0, true}.add(t)
+	memoryDevice{"M0001", "", 0xffff, 0xffff, 2048, 0x01, 0x01, 0, 0, "Hypervisor", "None", "None", "None", 0, 0, false}.add(t)
+	memoryDevice{"M0002", "", 0xffff, 0xffff, 2048, 0x01, 0x01, 0, 0, "Hypervisor", "None", "None", "None", 0, 0, false}.add(t)
+	mappedAddress(t, 0, 0x2fffff, 0, 0)
+	mappedAddress(t, 0x400000, 0x8fffff, 0, 0)
+	end := t.end()
+	write("edge-hypervisor-32.bin", entryPoint32(end, t.count))
+
+	// An ARM processor family only in the second family field and memory above 4GB mapped through extended
+	// addresses. Written with a 64-bit entry point.
+	t = &table{}
+	bios(t, "Vendor", "1.0", "01/01/2024")
+	system(t, "Vendor", "Product", "Not Specified", "ARM-SERIAL-0123456789", "Not Specified", "Not Specified")
+	baseboard(t, "Vendor", "Not Specified", "Not Specified", "Not Specified", "Not Specified")
+	processor{"CPU 0", "Vendor", "ARM CPU", 0xfe, 0x101, 0, 2500, 2500, 2, 2, 2}.add(t)
+	memoryArray(t, 3, 3, 4*1024*1024, 0, 1)
+	memoryDevice{"Not Specified", "Not Specified", 64, 64, 4096, 0x09, 0x1a, 3200, 3200, "Not Specified", "Not Specified", "Not Specified", "Not Specified", 0, 1200, false}.add(t)
+	mappedAddress(t, 0xffffffff, 0xffffffff, 0x40000000, 0x13fffffff)
+	write("edge-arm-64.bin", entryPoint64(t.end()))
+}
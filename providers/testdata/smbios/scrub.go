@@ -0,0 +1,146 @@
+//go:build ignore
+
+// Scrubs serial numbers, UUIDs and asset tags from an SMBIOS dump before it's added to this directory. Run with
+// `go run scrub.go <dump> <output>` from this directory.
+//
+// The dump can be the raw table from /sys/firmware/dmi/tables/DMI or a dmidecode --dump-bin file. Strings are
+// overwritten with a placeholder of the same length and the system UUID is zeroed, so every offset in the table stays
+// the same and the dump still decodes the way the original did.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+const placeholder = "SCRUBBED"
+
+// identifying lists the offsets of the string indexes that identify a machine per structure type
+var identifying = map[uint8][]int{
+	1:  {0x07},       // system serial number
+	2:  {0x07, 0x08}, // baseboard serial number and asset tag
+	3:  {0x07, 0x08}, // chassis serial number and asset tag
+	4:  {0x20, 0x21}, // processor serial number and asset tag
+	17: {0x18, 0x19}, // memory device serial number and asset tag
+	22: {0x07},       // battery serial number
+	39: {0x08, 0x09}, // power supply serial number and asset tag
+}
+
+// freeForm lists structure types whose strings are free-form and often hold serial numbers, like OEM strings and
+// system configuration options
+var freeForm = map[uint8]bool{11: true, 12: true}
+
+// notIdentifying are placeholders firmware puts in fields it doesn't fill, they are kept as the decoder handles them
+var notIdentifying = map[string]bool{
+	"": true, "None": true, "Not Specified": true, "Not Available": true, "Unknown": true, "Empty": true,
+	"To Be Filled By O.E.M.": true, "Default string": true, "No Asset Tag": true, "No Asset Information": true,
+}
+
+// table returns the offset and the end of the table in a dump
+func table(data []byte) (int, int, error) {
+	var address, size int
+	switch {
+	case bytes.HasPrefix(data, []byte("_SM3_")) && len(data) >= 0x18:
+		size = int(binary.LittleEndian.Uint32(data[0x0c:]))
+		address = int(binary.LittleEndian.Uint64(data[0x10:]))
+	case bytes.HasPrefix(data, []byte("_SM_")) && len(data) >= 0x1f:
+		size = int(binary.LittleEndian.Uint16(data[0x16:]))
+		address = int(binary.LittleEndian.Uint32(data[0x18:]))
+	default:
+		return 0, len(data), nil
+	}
+	if address >= len(data) {
+		return 0, 0, fmt.Errorf("table address 0x%x is outside of the dump", address)
+	}
+	end := address + size
+	if end > len(data) {
+		end = len(data)
+	}
+	return address, end, nil
+}
+
+// scrubString overwrites a string in place keeping its length
+func scrubString(s []byte) {
+	if notIdentifying[string(s)] {
+		return
+	}
+	for i := range s {
+		s[i] = placeholder[i%len(placeholder)]
+	}
+}
+
+func scrub(data []byte) error {
+	offset, end, err := table(data)
+	if err != nil {
+		return err
+	}
+
+	for offset+4 <= end {
+		structureType, length := data[offset], int(data[offset+1])
+		if length < 4 || offset+length > end {
+			return fmt.Errorf("structure at 0x%x is truncated", offset)
+		}
+		formatted := data[offset : offset+length]
+
+		// the string set follows the formatted area and ends with two zero bytes
+		var strings [][]byte
+		position := offset + length
+		for position < end && data[position] != 0 {
+			stringEnd := bytes.IndexByte(data[position:end], 0)
+			if stringEnd < 0 {
+				return fmt.Errorf("strings of the structure at 0x%x are truncated", offset)
+			}
+			strings = append(strings, data[position:position+stringEnd])
+			position += stringEnd + 1
+		}
+		if len(strings) == 0 {
+			position++
+		}
+		position++
+
+		if freeForm[structureType] {
+			for _, s := range strings {
+				scrubString(s)
+			}
+		}
+		for _, field := range identifying[structureType] {
+			if field < length && formatted[field] > 0 && int(formatted[field]) <= len(strings) {
+				scrubString(strings[formatted[field]-1])
+			}
+		}
+		if structureType == 1 && length >= 0x18 {
+			copy(formatted[0x08:0x18], make([]byte, 16))
+		}
+		if structureType == 22 && length >= 0x12 {
+			// SBDS serial number
+			copy(formatted[0x10:0x12], make([]byte, 2))
+		}
+
+		if structureType == 127 {
+			break
+		}
+		offset = position
+	}
+	return nil
+}
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: go run scrub.go <dump> <output>")
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(os.Args[1])
+	if err == nil {
+		err = scrub(data)
+	}
+	if err == nil {
+		err = os.WriteFile(os.Args[2], data, 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -5,14 +5,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 var apiKey = "not-set"
 
-func (report *Report) Send() {
+// Send submits the report. Wrong benchmark results mean a broken CPU or a bug, and neither belongs in the database, so
+// reports with failed benchmarks are never sent and an error is returned instead.
+func (report *Report) Send() error {
+	if failed := report.FailedBenchmarks(); len(failed) > 0 {
+		return fmt.Errorf("benchmarks failed (%v), the report will not be submitted", strings.Join(failed, ", "))
+	}
+
 	if apiKey == "not-set" {
 		fmt.Println("No API key set. Skipping report.")
-		return
+		return nil
 	}
 
 	fmt.Println("Sending report...")
@@ -39,4 +46,6 @@ func (report *Report) Send() {
 	if resp.StatusCode != 200 {
 		fmt.Printf("Bad status code: %v", resp.Status)
 	}
+
+	return nil
 }
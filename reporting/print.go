@@ -10,6 +10,7 @@ import (
 	"github.com/jedib0t/go-pretty/v6/text"
 	"github.com/klauspost/cpuid/v2"
 	"os"
+	"sort"
 	"strings"
 )
 
@@ -27,6 +28,9 @@ func (report *Report) Print(noColor bool) {
 	t.AppendRow(table.Row{"Instance type", report.InstanceType})
 	t.AppendRow(table.Row{"Region", report.Region})
 	t.AppendRow(table.Row{"Availability zone", report.AvailabilityZone})
+	if placement := report.Placement.String(); placement != "" {
+		t.AppendRow(table.Row{"Placement", placement})
+	}
 	t.AppendRow(table.Row{"Instance id", report.InstanceId})
 	t.AppendRow(table.Row{"Image id", report.ImageId})
 	if !noColor {
@@ -36,6 +40,12 @@ func (report *Report) Print(noColor bool) {
 
 	report.printCPU(noColor)
 	report.printMemory(noColor)
+	report.printPlatform(noColor)
+	report.printStorage(noColor)
+	report.printNetwork(noColor)
+	report.printMemoryLatency(noColor)
+	report.printNuma(noColor)
+	report.printBenchmarks(noColor)
 	report.printErrors(noColor)
 }
 
@@ -48,14 +58,13 @@ func (report *Report) printCPU(noColor bool) {
 	t.AppendRow(table.Row{"Vendor", cpuid.CPU.VendorString})
 	t.AppendRow(table.Row{"Vendor ID", fmt.Sprintf("%v", cpuid.CPU.VendorID.String())})
 	t.AppendRow(table.Row{"Family", fmt.Sprintf("%v", cpuid.CPU.Family)})
-	t.AppendRow(table.Row{"MHz", fmt.Sprintf("%v", cpuid.CPU.Hz/1_000_000)})
+	t.AppendRow(table.Row{"MHz", fmt.Sprintf("%v", report.CPU.MHz)})
 	t.AppendRow(table.Row{"Logical cores", fmt.Sprintf("%v", cpuid.CPU.LogicalCores)})
 	t.AppendRow(table.Row{"Physical cores", fmt.Sprintf("%v", cpuid.CPU.PhysicalCores)})
 	t.AppendRow(table.Row{"Thread per core", fmt.Sprintf("%v", cpuid.CPU.ThreadsPerCore)})
-	t.AppendRow(table.Row{"Boost frequency", fmt.Sprintf("%v", cpuid.CPU.BoostFreq)})
+	t.AppendRow(table.Row{"Boost frequency", fmt.Sprintf("%v", report.CPU.BoostFrequency)})
 	t.AppendRow(table.Row{"L1 Cache", fmt.Sprintf("%v instruction, %v data", int2bytes(cpuid.CPU.Cache.L1I), int2bytes(cpuid.CPU.Cache.L1D))})
 	t.AppendRow(table.Row{"L2 Cache", int2bytes(cpuid.CPU.Cache.L2)})
-	t.AppendRow(table.Row{"L2 Cache", int2bytes(cpuid.CPU.Cache.L2)})
 	t.AppendRow(table.Row{"L3 Cache", int2bytes(cpuid.CPU.Cache.L3)})
 	t.AppendRow(table.Row{"Cache line", fmt.Sprintf("%v", cpuid.CPU.CacheLine)})
 	t.AppendRow(table.Row{"Features", text.WrapSoft(strings.Join(cpuid.CPU.FeatureSet(), ", "), 80)})
@@ -71,10 +80,44 @@ func (report *Report) printMemory(noColor bool) {
 	t.SetAllowedRowLength(120)
 	t.SetTitle("Memory")
 	t.AppendRow(table.Row{"Total RAM", sigar.FormatSize(report.Memory.Total) + "B"})
+	t.AppendRow(table.Row{"Available RAM", sigar.FormatSize(report.Memory.Available) + "B"})
 	rowConfigAutoMerge := table.RowConfig{AutoMerge: true}
 	t.SetColumnConfigs([]table.ColumnConfig{
 		{Number: 1, AutoMerge: true},
 	})
+	report.appendSticks(t)
+	config := report.Memory.Config
+	for _, pool := range config.HugePages {
+		t.AppendRow(table.Row{"Huge pages", fmt.Sprintf("%vB on %v", sigar.FormatSize(pool.PageSize), pool.Node), fmt.Sprintf("%v total, %v free, %v surplus", pool.Total, pool.Free, pool.Surplus)}, rowConfigAutoMerge)
+	}
+	t.AppendRow(table.Row{"Transparent huge pages", "Enabled", config.TransparentHugePages}, rowConfigAutoMerge)
+	t.AppendRow(table.Row{"Transparent huge pages", "Defrag", config.TransparentHugePagesDefrag}, rowConfigAutoMerge)
+	t.AppendRow(table.Row{"VM", "Swappiness", fmt.Sprintf("%v", config.Swappiness)}, rowConfigAutoMerge)
+	t.AppendRow(table.Row{"VM", "Overcommit", fmt.Sprintf("mode %v, ratio %v%%", config.OvercommitMemory, config.OvercommitRatio)}, rowConfigAutoMerge)
+	for _, swap := range config.Swap {
+		swapType := swap.Type
+		if swap.Compressor != "" {
+			swapType += " " + swap.Compressor
+		}
+		t.AppendRow(table.Row{"Swap", swap.Name, fmt.Sprintf("%v, %vB, %vB used, priority %v", swapType, sigar.FormatSize(swap.Size), sigar.FormatSize(swap.Used), swap.Priority)}, rowConfigAutoMerge)
+	}
+	if len(config.Swap) == 0 {
+		t.AppendRow(table.Row{"Swap", "None", ""}, rowConfigAutoMerge)
+	}
+	t.AppendRow(table.Row{"Zswap", "Enabled", fmt.Sprintf("%v", config.Zswap.Enabled)}, rowConfigAutoMerge)
+	if config.Zswap.Enabled {
+		t.AppendRow(table.Row{"Zswap", "Compressor", config.Zswap.Compressor}, rowConfigAutoMerge)
+	}
+	t.AppendRow(table.Row{"KSM", "Run", config.KSM.Run}, rowConfigAutoMerge)
+	t.AppendRow(table.Row{"KSM", "Pages", fmt.Sprintf("%v shared, %v sharing", config.KSM.PagesShared, config.KSM.PagesSharing)}, rowConfigAutoMerge)
+	if !noColor {
+		t.SetStyle(table.StyleColoredMagentaWhiteOnBlack)
+	}
+	t.Render()
+}
+
+func (report *Report) appendSticks(t table.Writer) {
+	rowConfigAutoMerge := table.RowConfig{AutoMerge: true}
 	for i, stick := range report.Memory.Sticks {
 		stickCol := fmt.Sprintf("Stick #%v", i+1)
 		t.AppendRow(table.Row{stickCol, "Location", stick.Location}, rowConfigAutoMerge)
@@ -84,6 +127,317 @@ func (report *Report) printMemory(noColor bool) {
 		t.AppendRow(table.Row{stickCol, "Total width", fmt.Sprintf("%v-bit", stick.TotalWidth)}, rowConfigAutoMerge)
 		t.AppendRow(table.Row{stickCol, "Speed", fmt.Sprintf("%v MHz", stick.MHz)}, rowConfigAutoMerge)
 	}
+}
+
+// PrintSMBIOS prints only what was decoded from SMBIOS. It's used for table dumps of other machines where nothing else
+// in the report belongs to them.
+func (report *Report) PrintSMBIOS(noColor bool) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetAllowedRowLength(120)
+	t.SetTitle("Memory")
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, AutoMerge: true},
+	})
+	report.appendSticks(t)
+	if !noColor {
+		t.SetStyle(table.StyleColoredMagentaWhiteOnBlack)
+	}
+	t.Render()
+
+	report.printPlatform(noColor)
+	report.printErrors(noColor)
+}
+
+func (report *Report) printPlatform(noColor bool) {
+	platform := report.Platform
+	if platform.BIOS == (BiosReport{}) && platform.System == (SystemReport{}) && platform.Baseboard == (BaseboardReport{}) &&
+		len(platform.Processors) == 0 && len(platform.MemoryArrays) == 0 && len(platform.MappedAddressRanges) == 0 {
+		// SMBIOS needs root and the error already says so
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetAllowedRowLength(120)
+	t.SetTitle("Platform")
+	rowConfigAutoMerge := table.RowConfig{AutoMerge: true}
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, AutoMerge: true},
+	})
+	t.AppendRow(table.Row{"BIOS", "Vendor", report.Platform.BIOS.Vendor}, rowConfigAutoMerge)
+	t.AppendRow(table.Row{"BIOS", "Version", report.Platform.BIOS.Version}, rowConfigAutoMerge)
+	t.AppendRow(table.Row{"BIOS", "Release date", report.Platform.BIOS.ReleaseDate}, rowConfigAutoMerge)
+	t.AppendRow(table.Row{"System", "Manufacturer", report.Platform.System.Manufacturer}, rowConfigAutoMerge)
+	t.AppendRow(table.Row{"System", "Product", report.Platform.System.ProductName}, rowConfigAutoMerge)
+	t.AppendRow(table.Row{"System", "Version", report.Platform.System.Version}, rowConfigAutoMerge)
+	t.AppendRow(table.Row{"System", "SKU", report.Platform.System.SKU}, rowConfigAutoMerge)
+	t.AppendRow(table.Row{"System", "Family", report.Platform.System.Family}, rowConfigAutoMerge)
+	t.AppendRow(table.Row{"Baseboard", "Manufacturer", report.Platform.Baseboard.Manufacturer}, rowConfigAutoMerge)
+	t.AppendRow(table.Row{"Baseboard", "Product", report.Platform.Baseboard.Product}, rowConfigAutoMerge)
+	t.AppendRow(table.Row{"Baseboard", "Version", report.Platform.Baseboard.Version}, rowConfigAutoMerge)
+	for i, proc := range report.Platform.Processors {
+		procCol := fmt.Sprintf("Processor #%v", i+1)
+		t.AppendRow(table.Row{procCol, "Socket", proc.Socket}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{procCol, "Manufacturer", proc.Manufacturer}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{procCol, "Version", proc.Version}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{procCol, "Speed", fmt.Sprintf("%v MHz current, %v MHz max", proc.CurrentSpeed, proc.MaxSpeed)}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{procCol, "External clock", fmt.Sprintf("%v MHz", proc.ExternalClock)}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{procCol, "Cores", fmt.Sprintf("%v (%v enabled), %v threads", proc.CoreCount, proc.CoreEnabled, proc.ThreadCount)}, rowConfigAutoMerge)
+	}
+	for i, array := range report.Platform.MemoryArrays {
+		arrayCol := fmt.Sprintf("Memory array #%v", i+1)
+		t.AppendRow(table.Row{arrayCol, "Use", array.Use}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{arrayCol, "Error correction", array.ErrorCorrection}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{arrayCol, "Maximum capacity", sigar.FormatSize(array.MaximumCapacity) + "B"}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{arrayCol, "Devices", fmt.Sprintf("%v", array.Devices)}, rowConfigAutoMerge)
+	}
+	for i, mapped := range report.Platform.MappedAddressRanges {
+		t.AppendRow(table.Row{"Mapped addresses", fmt.Sprintf("Range #%v", i+1), fmt.Sprintf("%v-%v (%vB)", mapped.Start, mapped.End, sigar.FormatSize(mapped.Size))}, rowConfigAutoMerge)
+	}
+	if !noColor {
+		t.SetStyle(table.StyleColoredMagentaWhiteOnBlack)
+	}
+	t.Render()
+}
+
+func (report *Report) printStorage(noColor bool) {
+	if len(report.Storage.Devices) == 0 && len(report.Storage.Filesystems) == 0 {
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetAllowedRowLength(120)
+	t.SetTitle("Storage")
+	rowConfigAutoMerge := table.RowConfig{AutoMerge: true}
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, AutoMerge: true},
+	})
+	for _, device := range report.Storage.Devices {
+		media := "SSD"
+		if device.Rotational {
+			media = "HDD"
+		}
+		t.AppendRow(table.Row{device.Name, "Kind", fmt.Sprintf("%v (%v %v)", device.Kind, device.Transport, media)}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{device.Name, "Model", strings.TrimSpace(fmt.Sprintf("%v %v %v", device.Vendor, device.Model, device.Firmware))}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{device.Name, "Size", sigar.FormatSize(device.Size) + "B"}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{device.Name, "Sector size", fmt.Sprintf("%v logical, %v physical", device.LogicalSectorSize, device.PhysicalSectorSize)}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{device.Name, "Scheduler", device.Scheduler}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{device.Name, "Queues", fmt.Sprintf("depth %v, %v hardware queues", device.QueueDepth, device.HardwareQueues)}, rowConfigAutoMerge)
+	}
+	for _, filesystem := range report.Storage.Filesystems {
+		fsType := filesystem.Type
+		if filesystem.BlockDevice != "" {
+			fsType += " on " + filesystem.BlockDevice
+		}
+		if filesystem.MountPoint == "/" && (filesystem.Overlay || filesystem.InMemory) {
+			if !noColor {
+				fsType = text.FgYellow.Sprint(fsType + " (root is not on a disk)")
+			} else {
+				fsType += " (root is not on a disk)"
+			}
+		}
+		t.AppendRow(table.Row{filesystem.MountPoint, "Filesystem", fsType}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{filesystem.MountPoint, "Space", fmt.Sprintf("%vB total, %vB free, %vB available", sigar.FormatSize(filesystem.Size), sigar.FormatSize(filesystem.Free), sigar.FormatSize(filesystem.Available))}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{filesystem.MountPoint, "Inodes", fmt.Sprintf("%v total, %v free", filesystem.Inodes, filesystem.InodesFree)}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{filesystem.MountPoint, "Options", text.WrapSoft(strings.Join(filesystem.Options, ", "), 50)}, rowConfigAutoMerge)
+	}
+	if target := report.Storage.BenchmarkTarget; target.MountPoint != "" {
+		t.AppendRow(table.Row{"Benchmarked on", "Filesystem", fmt.Sprintf("%v (%v on %v)", target.MountPoint, target.Filesystem, target.BlockDevice)}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{"Benchmarked on", "Direct I/O", fmt.Sprintf("%v", target.DirectIO)}, rowConfigAutoMerge)
+	}
+	if !noColor {
+		t.SetStyle(table.StyleColoredMagentaWhiteOnBlack)
+	}
+	t.Render()
+}
+
+func (report *Report) printNetwork(noColor bool) {
+	if len(report.Network.Interfaces) == 0 && report.Network.EFADevices == 0 {
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetAllowedRowLength(120)
+	t.SetTitle("Network")
+	rowConfigAutoMerge := table.RowConfig{AutoMerge: true}
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, AutoMerge: true},
+	})
+	for _, iface := range report.Network.Interfaces {
+		speed := "unknown"
+		if iface.Speed > 0 {
+			speed = fmt.Sprintf("%v Mbps", iface.Speed)
+		}
+		t.AppendRow(table.Row{iface.Name, "Driver", strings.TrimSpace(fmt.Sprintf("%v %v", iface.Driver, iface.DriverVersion))}, rowConfigAutoMerge)
+		if iface.Firmware != "" {
+			t.AppendRow(table.Row{iface.Name, "Firmware", iface.Firmware}, rowConfigAutoMerge)
+		}
+		t.AppendRow(table.Row{iface.Name, "Link", fmt.Sprintf("%v, %v, MTU %v", iface.State, speed, iface.MTU)}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{iface.Name, "Queues", fmt.Sprintf("%v rx, %v tx", iface.RxQueues, iface.TxQueues)}, rowConfigAutoMerge)
+		t.AppendRow(table.Row{iface.Name, "SR-IOV", fmt.Sprintf("%v", iface.SRIOV)}, rowConfigAutoMerge)
+		if iface.Driver == "ena" {
+			t.AppendRow(table.Row{iface.Name, "ENA Express", fmt.Sprintf("%v", iface.ENAExpress)}, rowConfigAutoMerge)
+		}
+	}
+	t.AppendRow(table.Row{"EFA", "Devices", fmt.Sprintf("%v", report.Network.EFADevices)}, rowConfigAutoMerge)
+	if !noColor {
+		t.SetStyle(table.StyleColoredMagentaWhiteOnBlack)
+	}
+	t.Render()
+}
+
+func (report *Report) printMemoryLatency(noColor bool) {
+	if len(report.MemoryLatency.Curve) == 0 {
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetAllowedRowLength(120)
+	t.SetTitle("Memory Latency")
+	rowConfigAutoMerge := table.RowConfig{AutoMerge: true}
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, AutoMerge: true},
+	})
+	for _, point := range report.MemoryLatency.Curve {
+		t.AppendRow(table.Row{"Working set", int2bytes(point.Size), fmt.Sprintf("%.2f ns", point.Nanoseconds)}, rowConfigAutoMerge)
+	}
+	for _, level := range report.MemoryLatency.CacheLevels {
+		measured := "not found"
+		if level.MeasuredSize > 0 {
+			measured = "~" + int2bytes(level.MeasuredSize)
+		}
+		row := fmt.Sprintf("%v measured, %v from cpuid", measured, int2bytes(level.CpuidSize))
+		if level.Mismatch {
+			if !noColor {
+				row = text.FgRed.Sprint(row + " (mismatch)")
+			} else {
+				row += " (mismatch)"
+			}
+		}
+		t.AppendRow(table.Row{"Cache size", level.Level, row}, rowConfigAutoMerge)
+	}
+	if !noColor {
+		t.SetStyle(table.StyleColoredMagentaWhiteOnBlack)
+	}
+	t.Render()
+}
+
+func (report *Report) printNuma(noColor bool) {
+	if len(report.Numa.Nodes) < 2 {
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetAllowedRowLength(120)
+	t.SetTitle("NUMA (CPU node by memory node)")
+	header := table.Row{"Node"}
+	for _, node := range report.Numa.Nodes {
+		header = append(header, fmt.Sprintf("Memory on node %v", node.Id))
+	}
+	t.AppendHeader(header)
+	for i, node := range report.Numa.Nodes {
+		row := table.Row{fmt.Sprintf("CPUs on node %v", node.Id)}
+		for j := range report.Numa.Nodes {
+			cell := ""
+			if j < len(node.Distance) {
+				cell = fmt.Sprintf("distance %v", node.Distance[j])
+			}
+			if i < len(report.Numa.Latency) && report.Numa.Latency[i][j] > 0 {
+				cell += fmt.Sprintf("\n%.1f ns\n%.2f GB/s", report.Numa.Latency[i][j], report.Numa.Bandwidth[i][j])
+			}
+			row = append(row, cell)
+		}
+		t.AppendRow(row)
+	}
+	if !noColor {
+		t.SetStyle(table.StyleColoredMagentaWhiteOnBlack)
+	}
+	t.Render()
+}
+
+func (report *Report) printBenchmarks(noColor bool) {
+	if len(report.Benchmarks) == 0 {
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetAllowedRowLength(120)
+	t.SetTitle("Benchmarks")
+	rowConfigAutoMerge := table.RowConfig{AutoMerge: true}
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, AutoMerge: true},
+	})
+	var names []string
+	for name := range report.Benchmarks {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := report.Benchmarks[names[i]], report.Benchmarks[names[j]]
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		benchmark := report.Benchmarks[name]
+		better := benchmark.Better
+		if better == "" {
+			better = benchmark.Unit.Better()
+		}
+		result := fmt.Sprintf("%.7g %v (%v is better)", benchmark.Result, benchmark.Unit, better)
+		if benchmark.Failed {
+			result = "FAILED accuracy check, see errors"
+		}
+		if d := benchmark.Distribution; d != nil {
+			result += fmt.Sprintf("\np50 %.1f, p99 %.1f, p99.9 %.1f, max %.1f %v", d.P50, d.P99, d.P999, d.Max, d.Unit)
+		}
+		if benchmark.Peak > 0 {
+			result += fmt.Sprintf("\n%.1f%% of %.4g %v theoretical peak", 100*benchmark.Result/benchmark.Peak, benchmark.Peak, benchmark.Unit)
+		}
+		if benchmark.Kernel == "scalar" {
+			result += "\nscalar kernel without vector instructions"
+		} else if benchmark.Kernel != "" {
+			result += fmt.Sprintf("\n%v kernel", benchmark.Kernel)
+		}
+		if features := benchmark.CPUFeatures; features != nil {
+			if len(features) == 0 {
+				result += "\nno CPU features to speed it up"
+			} else {
+				result += "\nsped up by " + strings.Join(features, ", ")
+			}
+		}
+		if s := benchmark.Statistics; s != nil && len(s.Samples) > 1 {
+			result += fmt.Sprintf("\nmedian of %v runs, min %.4g, mean %.4g, stddev %.3g, CV %.1f%%", len(s.Samples), s.Min, s.Mean, s.StdDev, s.CV)
+			if len(s.Outliers) > 0 {
+				var outliers []string
+				for _, i := range s.Outliers {
+					outliers = append(outliers, fmt.Sprintf("run %v (%.4g)", i+1, s.Samples[i]))
+				}
+				result += "\noutliers " + strings.Join(outliers, ", ")
+			}
+		}
+		if len(benchmark.Streams) > 1 {
+			var streams []string
+			for _, stream := range benchmark.Streams {
+				streams = append(streams, fmt.Sprintf("%.2f", stream))
+			}
+			result += text.WrapSoft(fmt.Sprintf("\nper stream %v %v", strings.Join(streams, ", "), benchmark.Unit), 80)
+		}
+		if peer := benchmark.Peer; peer != nil {
+			if peer.InstanceType != "" {
+				result += fmt.Sprintf("\npeer %v %v in %v", peer.Cloud, peer.InstanceType, peer.AvailabilityZone)
+			} else {
+				result += "\npeer on unknown cloud"
+			}
+		}
+		t.AppendRow(table.Row{benchmark.Category, name, result}, rowConfigAutoMerge)
+	}
 	if !noColor {
 		t.SetStyle(table.StyleColoredMagentaWhiteOnBlack)
 	}
@@ -1,5 +1,12 @@
 package reporting
 
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
 type Report struct {
 	CloudZVersion    string                     `json:"cloud-z-version"`
 	Cloud            string                     `json:"cloud"`
@@ -8,12 +15,41 @@ type Report struct {
 	ImageId          string                     `json:"-"`
 	Region           string                     `json:"region"`
 	AvailabilityZone string                     `json:"availabilityZone"`
+	Placement        PlacementReport            `json:"placement"`
 	CPU              CpuReport                  `json:"cpu"`
 	Memory           MemoryReport               `json:"memory"`
+	Platform         PlatformReport             `json:"platform"`
+	MemoryLatency    MemoryLatencyReport        `json:"memoryLatency"`
+	Numa             NumaReport                 `json:"numa"`
+	Storage          StorageReport              `json:"storage"`
+	Network          NetworkReport              `json:"network"`
 	Benchmarks       map[string]BenchmarkReport `json:"benchmarks"`
 	Errors           []string                   `json:"errors,omitempty"`
 }
 
+type PlacementReport struct {
+	// Group is the placement group name or id which users pick so it's not submitted
+	Group string `json:"-"`
+	// Partition is the partition number on AWS or the fault domain on Azure
+	Partition string `json:"partition,omitempty"`
+	// Host is the physical host topology on GCP which could identify the instance so it's not submitted
+	Host string `json:"-"`
+}
+
+func (placement PlacementReport) String() string {
+	var parts []string
+	if placement.Group != "" {
+		parts = append(parts, "group "+placement.Group)
+	}
+	if placement.Partition != "" {
+		parts = append(parts, "partition "+placement.Partition)
+	}
+	if placement.Host != "" {
+		parts = append(parts, "host "+placement.Host)
+	}
+	return strings.Join(parts, ", ")
+}
+
 type CpuReport struct {
 	Description        string   `json:"description"`
 	Vendor             string   `json:"vendor"`
@@ -33,8 +69,10 @@ type CpuReport struct {
 }
 
 type MemoryReport struct {
-	Total  uint64              `json:"total"`
-	Sticks []MemoryStickReport `json:"sticks"`
+	Total     uint64              `json:"total"`
+	Available uint64              `json:"available"`
+	Sticks    []MemoryStickReport `json:"sticks"`
+	Config    MemoryConfigReport  `json:"config"`
 }
 
 type MemoryStickReport struct {
@@ -46,16 +84,411 @@ type MemoryStickReport struct {
 	MHz        uint16 `json:"mhz"`
 }
 
+type MemoryConfigReport struct {
+	HugePages                  []HugePagePoolReport `json:"hugePages"`
+	TransparentHugePages       string               `json:"transparentHugePages"`
+	TransparentHugePagesDefrag string               `json:"transparentHugePagesDefrag"`
+	Swappiness                 int                  `json:"swappiness"`
+	OvercommitMemory           int                  `json:"overcommitMemory"`
+	OvercommitRatio            int                  `json:"overcommitRatio"`
+	Swap                       []SwapDeviceReport   `json:"swap"`
+	Zswap                      ZswapReport          `json:"zswap"`
+	KSM                        KsmReport            `json:"ksm"`
+}
+
+type HugePagePoolReport struct {
+	Node     string `json:"node"`
+	PageSize uint64 `json:"pageSize"`
+	Total    int64  `json:"total"`
+	Free     int64  `json:"free"`
+	Surplus  int64  `json:"surplus"`
+}
+
+type SwapDeviceReport struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Size       uint64 `json:"size"`
+	Used       uint64 `json:"used"`
+	Priority   int    `json:"priority"`
+	Compressor string `json:"compressor,omitempty"`
+}
+
+type ZswapReport struct {
+	Enabled    bool   `json:"enabled"`
+	Compressor string `json:"compressor"`
+}
+
+type KsmReport struct {
+	Run          string `json:"run"`
+	PagesShared  int64  `json:"pagesShared"`
+	PagesSharing int64  `json:"pagesSharing"`
+}
+
+type MemoryLatencyReport struct {
+	Curve       []LatencyPointReport `json:"curve"`
+	CacheLevels []CacheLevelReport   `json:"cacheLevels"`
+}
+
+type LatencyPointReport struct {
+	Size        int     `json:"size"`
+	Nanoseconds float64 `json:"nanoseconds"`
+}
+
+type CacheLevelReport struct {
+	Level        string `json:"level"`
+	CpuidSize    int    `json:"cpuidSize"`
+	MeasuredSize int    `json:"measuredSize"`
+	Mismatch     bool   `json:"mismatch"`
+}
+
+type StorageReport struct {
+	Devices         []StorageDeviceReport `json:"devices"`
+	Filesystems     []FilesystemReport    `json:"filesystems"`
+	BenchmarkTarget StorageTargetReport   `json:"benchmarkTarget"`
+}
+
+type StorageTargetReport struct {
+	MountPoint  string `json:"mountPoint"`
+	Filesystem  string `json:"filesystem"`
+	BlockDevice string `json:"blockDevice"`
+	Available   uint64 `json:"-"`
+	DirectIO    bool   `json:"directIO"`
+}
+
+type StorageDeviceReport struct {
+	Name               string `json:"name"`
+	Transport          string `json:"transport"`
+	Kind               string `json:"kind"`
+	Vendor             string `json:"vendor"`
+	Model              string `json:"model"`
+	Firmware           string `json:"firmware"`
+	Size               uint64 `json:"size"`
+	Rotational         bool   `json:"rotational"`
+	LogicalSectorSize  int    `json:"logicalSectorSize"`
+	PhysicalSectorSize int    `json:"physicalSectorSize"`
+	Scheduler          string `json:"scheduler"`
+	QueueDepth         int    `json:"queueDepth"`
+	HardwareQueues     int    `json:"hardwareQueues"`
+}
+
+type FilesystemReport struct {
+	// Path is where the filesystem is mounted, and MountPoint is the same with user names and volume labels redacted
+	Path        string   `json:"-"`
+	MountPoint  string   `json:"mountPoint"`
+	Source      string   `json:"source"`
+	BlockDevice string   `json:"blockDevice"`
+	Type        string   `json:"type"`
+	Options     []string `json:"options"`
+	Overlay     bool     `json:"overlay"`
+	InMemory    bool     `json:"inMemory"`
+	Size        uint64   `json:"size"`
+	Free        uint64   `json:"free"`
+	Available   uint64   `json:"available"`
+	Inodes      uint64   `json:"inodes"`
+	InodesFree  uint64   `json:"inodesFree"`
+}
+
+type NetworkReport struct {
+	Interfaces []NetworkInterfaceReport `json:"interfaces"`
+	EFADevices int                      `json:"efaDevices"`
+}
+
+type NetworkInterfaceReport struct {
+	Name          string `json:"name"`
+	Driver        string `json:"driver"`
+	DriverVersion string `json:"driverVersion"`
+	Firmware      string `json:"firmware"`
+	State         string `json:"state"`
+	Speed         int    `json:"speed"`
+	MTU           int    `json:"mtu"`
+	RxQueues      int    `json:"rxQueues"`
+	TxQueues      int    `json:"txQueues"`
+	SRIOV         bool   `json:"sriov"`
+	ENAExpress    bool   `json:"enaExpress"`
+}
+
+type NumaReport struct {
+	Nodes     []NumaNodeReport `json:"nodes"`
+	Latency   [][]float64      `json:"latency,omitempty"`
+	Bandwidth [][]float64      `json:"bandwidth,omitempty"`
+}
+
+type NumaNodeReport struct {
+	Id       int    `json:"id"`
+	CPUs     []int  `json:"cpus"`
+	Memory   uint64 `json:"memory"`
+	Distance []int  `json:"distance"`
+}
+
+type PlatformReport struct {
+	BIOS                BiosReport                 `json:"bios"`
+	System              SystemReport               `json:"system"`
+	Baseboard           BaseboardReport            `json:"baseboard"`
+	Processors          []ProcessorSocketReport    `json:"processors"`
+	MemoryArrays        []MemoryArrayReport        `json:"memoryArrays"`
+	MappedAddressRanges []MappedAddressRangeReport `json:"mappedAddressRanges"`
+}
+
+type BiosReport struct {
+	Vendor      string `json:"vendor"`
+	Version     string `json:"version"`
+	ReleaseDate string `json:"releaseDate"`
+}
+
+type SystemReport struct {
+	Manufacturer string `json:"manufacturer"`
+	ProductName  string `json:"productName"`
+	Version      string `json:"version"`
+	SKU          string `json:"sku"`
+	Family       string `json:"family"`
+}
+
+type BaseboardReport struct {
+	Manufacturer string `json:"manufacturer"`
+	Product      string `json:"product"`
+	Version      string `json:"version"`
+}
+
+type ProcessorSocketReport struct {
+	Socket        string `json:"socket"`
+	Manufacturer  string `json:"manufacturer"`
+	Version       string `json:"version"`
+	ExternalClock uint16 `json:"externalClock"`
+	MaxSpeed      uint16 `json:"maxSpeed"`
+	CurrentSpeed  uint16 `json:"currentSpeed"`
+	CoreCount     int    `json:"coreCount"`
+	CoreEnabled   int    `json:"coreEnabled"`
+	ThreadCount   int    `json:"threadCount"`
+}
+
+type MemoryArrayReport struct {
+	Use             string `json:"use"`
+	ErrorCorrection string `json:"errorCorrection"`
+	MaximumCapacity uint64 `json:"maximumCapacity"`
+	Devices         uint16 `json:"devices"`
+}
+
+type MappedAddressRangeReport struct {
+	Start          string `json:"start"`
+	End            string `json:"end"`
+	Size           uint64 `json:"size"`
+	PartitionWidth uint8  `json:"partitionWidth"`
+}
+
 type UnitType string
 
 const (
-	Seconds UnitType = "seconds"
+	Seconds             UnitType = "seconds"
+	GigabytesPerSecond  UnitType = "GB/s"
+	Nanoseconds         UnitType = "ns"
+	MegabytesPerSecond  UnitType = "MB/s"
+	OperationsPerSecond UnitType = "ops/s"
+	Microseconds        UnitType = "us"
+	GigabitsPerSecond   UnitType = "Gbps"
+	PacketsPerSecond    UnitType = "pps"
+	Percent             UnitType = "%"
+	BytesPerSecond      UnitType = "B/s"
+	GFLOPS              UnitType = "GFLOPS"
+	Points              UnitType = "points"
+)
+
+// Better returns which direction is better for results in this unit.
+func (unit UnitType) Better() BetterType {
+	switch unit {
+	case Seconds, Nanoseconds, Microseconds, Percent:
+		return LowerIsBetter
+	}
+	return HigherIsBetter
+}
+
+type BetterType string
+
+const (
+	HigherIsBetter BetterType = "higher"
+	LowerIsBetter  BetterType = "lower"
+)
+
+type CategoryType string
+
+const (
+	CPU     CategoryType = "cpu"
+	Memory  CategoryType = "memory"
+	Storage CategoryType = "storage"
+	Network CategoryType = "network"
 )
 
 type BenchmarkReport struct {
-	Version int      `json:"version"`
-	Result  float64  `json:"result"`
-	Unit    UnitType `json:"unit"`
+	Version  int          `json:"version"`
+	Category CategoryType `json:"category"`
+	Result   float64      `json:"result"`
+	Unit     UnitType     `json:"unit"`
+	Better   BetterType   `json:"better"`
+	// Failed is set when the benchmark got the wrong answer and Result is meaningless
+	Failed       bool                `json:"failed,omitempty"`
+	Distribution *DistributionReport `json:"distribution,omitempty"`
+	// Statistics summarizes repeated runs, Result is their median
+	Statistics *StatisticsReport `json:"statistics,omitempty"`
+	// Streams has the result of every parallel stream in network benchmarks
+	Streams []float64 `json:"streams,omitempty"`
+	// Peer is the other instance in network benchmarks
+	Peer *PeerReport `json:"peer,omitempty"`
+	// Peak is the theoretical best result on this instance
+	Peak float64 `json:"peak,omitempty"`
+	// Kernel names the code that ran when it depends on the CPU, scalar means no vector instructions were used
+	Kernel string `json:"kernel,omitempty"`
+	// CPUFeatures lists features of this CPU that speed up the benchmark, like AESNI for AES
+	CPUFeatures []string `json:"cpuFeatures,omitempty"`
+}
+
+type PeerReport struct {
+	Cloud            string `json:"cloud"`
+	InstanceType     string `json:"instanceType"`
+	Region           string `json:"region"`
+	AvailabilityZone string `json:"availabilityZone"`
+}
+
+type DistributionReport struct {
+	Unit UnitType `json:"unit"`
+	P50  float64  `json:"p50"`
+	P99  float64  `json:"p99"`
+	P999 float64  `json:"p99.9"`
+	Max  float64  `json:"max"`
+}
+
+type StatisticsReport struct {
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+	// CV is the coefficient of variation in percent
+	CV      float64   `json:"cv"`
+	Samples []float64 `json:"samples"`
+	// Outliers are the indexes of samples that are far from the median, like a run that shared the CPU with a noisy
+	// neighbor
+	Outliers []int `json:"outliers,omitempty"`
+}
+
+// outlierScore is the modified z-score above which a sample is an outlier, as recommended by Iglewicz and Hoaglin.
+const outlierScore = 3.5
+
+// sortedMedian returns the median of sorted values.
+func sortedMedian(sorted []float64) float64 {
+	if len(sorted)%2 == 0 {
+		return (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+	return sorted[len(sorted)/2]
+}
+
+// outliers returns the indexes of samples whose modified z-score, which uses the median absolute deviation instead of
+// the standard deviation so the outliers themselves don't hide each other, is above outlierScore.
+func outliers(samples []float64, median float64) []int {
+	deviations := make([]float64, len(samples))
+	for i, sample := range samples {
+		deviations[i] = math.Abs(sample - median)
+	}
+	sort.Float64s(deviations)
+	mad := sortedMedian(deviations)
+	if mad == 0 {
+		// more than half of the samples are the same, so nothing stands out from them in a meaningful way
+		return nil
+	}
+
+	var result []int
+	for i, sample := range samples {
+		if 0.6745*math.Abs(sample-median)/mad > outlierScore {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// NewStatistics summarizes the results of repeated runs. Samples are kept in the order they ran.
+func NewStatistics(samples []float64) *StatisticsReport {
+	if len(samples) == 0 {
+		return &StatisticsReport{}
+	}
+
+	sorted := append([]float64{}, samples...)
+	sort.Float64s(sorted)
+
+	median := sortedMedian(sorted)
+
+	var sum float64
+	for _, sample := range samples {
+		sum += sample
+	}
+	mean := sum / float64(len(samples))
+
+	var stdDev float64
+	if len(samples) > 1 {
+		var squares float64
+		for _, sample := range samples {
+			squares += (sample - mean) * (sample - mean)
+		}
+		stdDev = math.Sqrt(squares / float64(len(samples)-1))
+	}
+
+	var cv float64
+	if mean != 0 {
+		cv = 100 * stdDev / math.Abs(mean)
+	}
+
+	return &StatisticsReport{
+		Min:      sorted[0],
+		Median:   median,
+		Mean:     mean,
+		StdDev:   stdDev,
+		CV:       cv,
+		Samples:  samples,
+		Outliers: outliers(samples, median),
+	}
+}
+
+// percentile returns the p-th percentile of sorted latencies in microseconds.
+func percentile(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := int(float64(len(sorted)-1) * p / 100)
+	return float64(sorted[index].Nanoseconds()) / 1000
+}
+
+// NewLatencyDistribution sorts latencies and summarizes them in microseconds.
+func NewLatencyDistribution(latencies []time.Duration) *DistributionReport {
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+
+	return &DistributionReport{
+		Unit: Microseconds,
+		P50:  percentile(latencies, 50),
+		P99:  percentile(latencies, 99),
+		P999: percentile(latencies, 99.9),
+		Max:  percentile(latencies, 100),
+	}
+}
+
+// AddBenchmark adds a benchmark result. Better defaults to the usual direction for the unit.
+func (report *Report) AddBenchmark(name string, benchmark BenchmarkReport) {
+	if report.Benchmarks == nil {
+		report.Benchmarks = map[string]BenchmarkReport{}
+	}
+	if benchmark.Better == "" {
+		benchmark.Better = benchmark.Unit.Better()
+	}
+	report.Benchmarks[name] = benchmark
+}
+
+// FailedBenchmarks returns the names of benchmarks that got the wrong answer.
+func (report *Report) FailedBenchmarks() []string {
+	var failed []string
+	for name, benchmark := range report.Benchmarks {
+		if benchmark.Failed {
+			failed = append(failed, name)
+		}
+	}
+	sort.Strings(failed)
+	return failed
 }
 
 func (report *Report) AddError(error string) {